@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sync-image/internal/bundle"
+	"sync-image/internal/config"
+	"sync-image/internal/docker"
+	"sync-image/pkg/logger"
+	"sync-image/pkg/utils"
+)
+
+// runBundleExport exports the listed images into an offline bundle archive
+func runBundleExport(ctx context.Context, cfg *config.Config, log logger.Logger) error {
+	images, err := bundle.ReadImageList(*bundleExportList)
+	if err != nil {
+		return err
+	}
+
+	var base *bundle.Index
+	if *bundleExportBase != "" {
+		if base, err = bundle.LoadIndex(*bundleExportBase); err != nil {
+			return fmt.Errorf("failed to load base index: %w", err)
+		}
+		log.Info("Incremental export against base bundle created at %s", base.Created)
+	}
+
+	platforms := *bundleExportPlats
+	if platforms == "" {
+		platforms = cfg.Platforms
+	}
+	var platformList []string
+	if platforms != "all" {
+		platformList = strings.Split(platforms, ",")
+	}
+
+	client := createRegistryClient(cfg, log)
+	exporter := bundle.NewExporter(client, platformList, log)
+
+	index, err := exporter.Export(ctx, images, *bundleExportOutput, base)
+	if err != nil {
+		return err
+	}
+
+	indexPath := *bundleExportIndex
+	if indexPath == "" {
+		indexPath = *bundleExportOutput + ".index.json"
+	}
+	if err := index.Save(indexPath); err != nil {
+		return err
+	}
+
+	included := 0
+	for _, blob := range index.Blobs {
+		if blob.Included {
+			included++
+		}
+	}
+	log.Info("Exported %d images (%d of %d blobs included) to %s, index written to %s",
+		len(index.Images), included, len(index.Blobs), *bundleExportOutput, indexPath)
+	return nil
+}
+
+// runBundleImport pushes the images of an offline bundle archive to the target registry
+func runBundleImport(ctx context.Context, cfg *config.Config, log logger.Logger) error {
+	targetRegistry, targetNamespace := *bundleImportTarget, ""
+	if i := strings.Index(targetRegistry, "/"); i >= 0 {
+		targetRegistry, targetNamespace = targetRegistry[:i], targetRegistry[i+1:]
+	}
+
+	naming := cfg.GetEffectiveGenericConfig().TargetNaming()
+	if *bundleImportNaming != "" {
+		strategy, err := utils.ParseNamingStrategy(*bundleImportNaming)
+		if err != nil {
+			return err
+		}
+		naming.Strategy = strategy
+	}
+
+	transformer := docker.NewImageTransformer(cfg.Rules, cfg.RulesMode, log)
+	targetName := func(reference string) (string, error) {
+		result, err := transformer.Transform(reference, docker.Target{
+			Registry:  targetRegistry,
+			Namespace: targetNamespace,
+			Naming:    naming,
+		})
+		if err != nil {
+			return "", err
+		}
+		return result.TargetImage, nil
+	}
+
+	client := createRegistryClient(cfg, log)
+	importer := bundle.NewImporter(client, log)
+
+	index, err := importer.Import(ctx, *bundleImportFile, targetName)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Imported %d images into %s", len(index.Images), *bundleImportTarget)
+	return nil
+}
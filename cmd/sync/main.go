@@ -5,8 +5,8 @@
 // Quay.io, GitHub Container Registry (ghcr.io) and other foreign registries
 // to Huawei SWR registry.
 //
-// The tool listens to GitHub Issues to trigger image synchronization and
-// supports multi-architecture image builds.
+// The tool listens to GitHub Issues (or GitLab / Gitea issues) to trigger image
+// synchronization and supports multi-architecture image builds.
 package main
 
 import (
@@ -19,8 +19,11 @@ import (
 	"sync-image/internal/config"
 	"sync-image/internal/docker"
 	githubclient "sync-image/internal/github"
+	"sync-image/internal/mapping"
+	"sync-image/internal/puller"
 	"sync-image/internal/registry"
 	"sync-image/internal/service"
+	"sync-image/internal/source"
 	"sync-image/pkg/logger"
 )
 
@@ -43,11 +46,56 @@ var (
 	// Application parameters
 	logLevel = kingpin.Flag("log.level", "Log level").Default("info").String()
 	debug    = kingpin.Flag("debug", "Enable debug mode").Bool()
+
+	// Commands
+	syncCmd           = kingpin.Command("sync", "Process pending image request issues and sync images").Default()
+	syncDryRun        = syncCmd.Flag("dry-run", "Print the sync plan without pushing, labeling or closing issues").Bool()
+	syncDryRunComment = syncCmd.Flag("dry-run.comment", "Also post the sync plan as an issue comment in dry-run mode").Bool()
+	syncWorkers       = syncCmd.Flag("workers", "Number of issues to process concurrently (default: app.workers)").Int()
+
+	bundleCmd          = kingpin.Command("bundle", "Export and import offline image bundles")
+	bundleExportCmd    = bundleCmd.Command("export", "Export images into an offline bundle archive")
+	bundleExportList   = bundleExportCmd.Flag("list", "File with one image reference per line").Short('l').Required().String()
+	bundleExportOutput = bundleExportCmd.Flag("output", "Bundle archive path").Short('o').Default("bundle.tar").String()
+	bundleExportBase   = bundleExportCmd.Flag("base", "Index file of a previous bundle; blobs recorded there are not included").String()
+	bundleExportIndex  = bundleExportCmd.Flag("index", "Path to write the bundle index (default: <output>.index.json)").String()
+	bundleExportPlats  = bundleExportCmd.Flag("platforms", "Platforms to export, 'all' for every platform (default: configured platforms)").String()
+	bundleImportCmd    = bundleCmd.Command("import", "Push images from an offline bundle archive to a registry")
+	bundleImportFile   = bundleImportCmd.Arg("bundle", "Bundle archive path").Required().String()
+	bundleImportTarget = bundleImportCmd.Flag("target", "Target registry, optionally followed by a namespace (registry/namespace)").Required().String()
+	bundleImportNaming = bundleImportCmd.Flag("naming", "Target naming strategy: last-segment, flatten, full-path, hash-suffix (default: generic registry setting)").String()
+
+	rulesCmd        = kingpin.Command("rules", "Inspect image transformation rules")
+	rulesTestCmd    = rulesCmd.Command("test", "Show how image references are transformed, without Docker or GitHub")
+	rulesTestImages = rulesTestCmd.Arg("images", "Image references to transform").Strings()
+	rulesTestFile   = rulesTestCmd.Flag("file", "File with one image reference per line").Short('f').String()
+	rulesTestOutput = rulesTestCmd.Flag("output", "Output format: table or json").Short('o').Default("table").Enum("table", "json")
+
+	pullCmd       = kingpin.Command("pull", "Pull the mirrored copy of an upstream image and tag it with the upstream name")
+	pullImage     = pullCmd.Arg("image", "Upstream image reference").Required().String()
+	pullRuntime   = pullCmd.Flag("runtime", "Container runtime: auto, docker, nerdctl, podman, ctr, crictl").Default(puller.RuntimeAuto).Enum(puller.Runtimes()...)
+	pullNamespace = pullCmd.Flag("namespace", "containerd namespace for ctr, nerdctl and crictl").Default(puller.DefaultNamespace).String()
+	pullDryRun    = pullCmd.Flag("dry-run", "Print the commands without running them").Bool()
+
+	reconcileCmd    = kingpin.Command("reconcile", "Sync the images declared in a manifest that are missing or out of date at the destination")
+	reconcileFile   = reconcileCmd.Flag("file", "Image manifest (images.yaml)").Short('f').Required().String()
+	reconcileDryRun = reconcileCmd.Flag("dry-run", "Print the plan without pushing").Bool()
+	reconcileBase   = reconcileCmd.Flag("base", "Manifest before the change; images only listed there are planned as removed").String()
+	reconcileCheck  = reconcileCmd.Flag("check-run", "Publish the plan (with --dry-run) or the sync results as a GitHub check run").Bool()
+	reconcileSHA    = reconcileCmd.Flag("sha", "Commit to attach the check run to (default: $GITHUB_SHA)").String()
+
+	serveCmd    = kingpin.Command("serve", "Receive GitHub webhooks and sync requested images as a long-running service")
+	serveListen = serveCmd.Flag("listen", "Listen address (default: server.listen)").String()
+
+	mirrorsCmd    = kingpin.Command("mirrors", "Generate runtime registry mirror configuration from the transformation rules")
+	mirrorsFormat = mirrorsCmd.Flag("format", "Configuration format: containerd (certs.d hosts.toml), crio (registries.conf) or docker (daemon.json)").Default("containerd").Enum("containerd", "crio", "docker")
+	mirrorsOutput = mirrorsCmd.Flag("output", "Directory to write into, e.g. /etc/containerd, /etc/containers or /etc/docker (default: print to stdout)").Short('o').String()
+	mirrorsHosts  = mirrorsCmd.Flag("host", "Additional upstream registry host to generate a mirror for (repeatable)").Strings()
 )
 
 func main() {
 	kingpin.HelpFlag.Short('h')
-	kingpin.Parse()
+	command := kingpin.Parse()
 
 	// Show version information
 	if *showVersion {
@@ -65,6 +113,9 @@ func main() {
 
 	if *debug {
 		log.SetLevel(logger.DEBUG)
+	} else if command == rulesTestCmd.FullCommand() || command == mirrorsCmd.FullCommand() {
+		// Keep stdout for the report
+		log.SetLevel(logger.ERROR)
 	}
 
 	log.Info("Starting image sync tool v%s (commit: %s)", Version, Commit)
@@ -80,6 +131,51 @@ func main() {
 	safeConfig := cfg.GetSafeConfig()
 	log.Debug("Loaded configuration: %+v", safeConfig)
 
+	ctx := context.Background()
+
+	switch command {
+	case bundleExportCmd.FullCommand():
+		if err := runBundleExport(ctx, cfg, log); err != nil {
+			log.Error("Failed to export bundle: %v", err)
+			os.Exit(1)
+		}
+	case bundleImportCmd.FullCommand():
+		if err := runBundleImport(ctx, cfg, log); err != nil {
+			log.Error("Failed to import bundle: %v", err)
+			os.Exit(1)
+		}
+	case rulesTestCmd.FullCommand():
+		if err := runRulesTest(ctx, cfg, log); err != nil {
+			log.Error("Rules test failed: %v", err)
+			os.Exit(1)
+		}
+	case pullCmd.FullCommand():
+		if err := runPull(ctx, cfg, log); err != nil {
+			log.Error("Failed to pull image: %v", err)
+			os.Exit(1)
+		}
+	case reconcileCmd.FullCommand():
+		if err := runReconcile(ctx, cfg, log); err != nil {
+			log.Error("Reconcile failed: %v", err)
+			os.Exit(1)
+		}
+	case serveCmd.FullCommand():
+		if err := runServe(ctx, cfg, log); err != nil {
+			log.Error("Webhook server failed: %v", err)
+			os.Exit(1)
+		}
+	case mirrorsCmd.FullCommand():
+		if err := runMirrors(cfg, log); err != nil {
+			log.Error("Failed to generate mirror configuration: %v", err)
+			os.Exit(1)
+		}
+	case syncCmd.FullCommand():
+		runSync(ctx, cfg, log)
+	}
+}
+
+// runSync processes pending image request issues and syncs the requested images
+func runSync(ctx context.Context, cfg *config.Config, log logger.Logger) {
 	// Create application instance
 	app, err := createApp(cfg, log)
 	if err != nil {
@@ -94,8 +190,27 @@ func main() {
 		}
 	}()
 
+	// Only print the plan in dry-run mode
+	if cfg.App.DryRun {
+		if err := app.syncService.PlanIssues(ctx); err != nil {
+			log.Error("Failed to plan Issues: %v", err)
+			os.Exit(1)
+		}
+		log.Info("Dry-run completed, nothing was pushed")
+		return
+	}
+
+	// Issue comments only carry slash commands for the commented issue
+	if cfg.GitHub.EventName == "issue_comment" {
+		if err := app.syncService.ProcessComment(ctx); err != nil {
+			log.Error("Failed to process comment: %v", err)
+			os.Exit(1)
+		}
+		log.Info("Comment processed")
+		return
+	}
+
 	// Run application
-	ctx := context.Background()
 	if err := app.syncService.ProcessIssues(ctx); err != nil {
 		log.Error("Failed to process Issues: %v", err)
 		os.Exit(1)
@@ -128,6 +243,15 @@ func loadConfig() (*config.Config, error) {
 	if *githubRunID != "" {
 		cfg.GitHub.RunID = *githubRunID
 	}
+	if *syncDryRun {
+		cfg.App.DryRun = true
+	}
+	if *syncDryRunComment {
+		cfg.App.DryRunComment = true
+	}
+	if *syncWorkers > 0 {
+		cfg.App.Workers = *syncWorkers
+	}
 	// Docker command line parameters have been completely removed, use config file or environment variables
 
 	return cfg, nil
@@ -135,25 +259,91 @@ func loadConfig() (*config.Config, error) {
 
 // createBuilderConfig creates builder configuration from config
 func createBuilderConfig(cfg *config.Config) *docker.BuilderConfig {
+	// Logins for destinations that transformation rules route images to
+	var extraLogins []docker.RegistryLogin
+	for _, name := range cfg.DestinationNames() {
+		destination := cfg.GetDestination(name)
+		extraLogins = append(extraLogins, docker.RegistryLogin{
+			Registry: destination.Registry,
+			Username: destination.Username,
+			Password: destination.Password,
+		})
+	}
+
 	// Prefer using generic configuration
 	if genericConfig := cfg.GetEffectiveGenericConfig(); genericConfig != nil {
 		return &docker.BuilderConfig{
-			Registry:  genericConfig.Registry,
-			Namespace: genericConfig.Namespace,
-			Username:  genericConfig.Username,
-			Password:  genericConfig.Password,
-			Platforms: cfg.Platforms,
+			Registry:    genericConfig.Registry,
+			Namespace:   genericConfig.Namespace,
+			Username:    genericConfig.Username,
+			Password:    genericConfig.Password,
+			Platforms:   cfg.Platforms,
+			ExtraLogins: extraLogins,
 		}
 	}
 
 	// If no generic configuration, create default configuration
 	return &docker.BuilderConfig{
-		Registry:  "",
-		Namespace: "",
-		Username:  "",
-		Password:  "",
-		Platforms: cfg.Platforms,
+		Registry:    "",
+		Namespace:   "",
+		Username:    "",
+		Password:    "",
+		Platforms:   cfg.Platforms,
+		ExtraLogins: extraLogins,
+	}
+}
+
+// createDestinationTargets converts the configured rule destinations into transformer targets
+func createDestinationTargets(cfg *config.Config) map[string]docker.Target {
+	targets := make(map[string]docker.Target, len(cfg.Destinations))
+	for _, name := range cfg.DestinationNames() {
+		targets[name] = docker.TargetFromConfig(cfg.GetDestination(name))
 	}
+	return targets
+}
+
+// createRegistryClient creates a registry API client with the configured credentials
+func createRegistryClient(cfg *config.Config, log logger.Logger) *registry.Client {
+	client := registry.NewClient(log)
+
+	if genericConfig := cfg.GetEffectiveGenericConfig(); genericConfig != nil && genericConfig.Username != "" {
+		client.SetCredentials(genericConfig.Registry, registry.Credentials{
+			Username: genericConfig.Username,
+			Password: genericConfig.Password,
+		})
+	}
+
+	for _, name := range cfg.DestinationNames() {
+		if destination := cfg.GetDestination(name); destination.Username != "" {
+			client.SetCredentials(destination.Registry, registry.Credentials{
+				Username: destination.Username,
+				Password: destination.Password,
+			})
+		}
+	}
+
+	return client
+}
+
+// createMappingIndex creates the source-to-target mapping index for the configured backend, or nil when disabled
+func createMappingIndex(cfg *config.Config, client *registry.Client) (*mapping.Index, error) {
+	var store mapping.Store
+	switch cfg.Mapping.Backend {
+	case "file":
+		store = mapping.NewFileStore(cfg.Mapping.File)
+	case "registry":
+		registryStore, err := mapping.NewRegistryStore(client, cfg.MappingReference())
+		if err != nil {
+			return nil, err
+		}
+		store = registryStore
+	default:
+		return nil, nil
+	}
+
+	index := mapping.NewIndex(store)
+	index.SetRedirects(cfg.Redirects)
+	return index, nil
 }
 
 // loadConfigWithoutValidation loads configuration without validation
@@ -165,8 +355,10 @@ func loadConfigWithoutValidation(configPath string) (*config.Config, error) {
 		if fmt.Sprintf("%v", err) != "config validation failed: GitHub token is required" {
 			return nil, err
 		}
-		// If validation error, create default configuration and load from environment
-		cfg = config.DefaultConfig()
+		// If validation error, load the configuration file and environment without validation
+		if cfg, err = config.Load(configPath); err != nil {
+			return nil, err
+		}
 		// Load configuration from environment variables
 		loadConfigFromEnvironment(cfg)
 	}
@@ -188,6 +380,15 @@ func loadConfigFromEnvironment(cfg *config.Config) {
 	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
 		cfg.GitHub.RunID = runID
 	}
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		cfg.GitHub.APIURL = apiURL
+	}
+	if eventName := os.Getenv("GITHUB_EVENT_NAME"); eventName != "" {
+		cfg.GitHub.EventName = eventName
+	}
+	if eventPath := os.Getenv("GITHUB_EVENT_PATH"); eventPath != "" {
+		cfg.GitHub.EventPath = eventPath
+	}
 	// No longer support DOCKER_* environment variables, use GENERIC_* environment variables
 	// No longer support old Huawei cloud environment variables, use HUAWEI_SWR_* environment variables
 }
@@ -199,28 +400,74 @@ type App struct {
 	logger      logger.Logger
 }
 
+// createRequestSource creates the request source for source.type: GitHub issues by default, or GitLab / Gitea issues
+func createRequestSource(cfg *config.Config, log logger.Logger) (source.RequestSource, error) {
+	switch cfg.Source.Type {
+	case config.SourceGitLab:
+		log.Info("Reading requests from GitLab project %s at %s", cfg.Source.Project, cfg.Source.URL)
+		return source.NewGitLabSource(&cfg.Source, log), nil
+	case config.SourceGitea:
+		log.Info("Reading requests from Gitea repository %s at %s", cfg.Source.Project, cfg.Source.URL)
+		return source.NewGiteaSource(&cfg.Source, log), nil
+	}
+
+	client, err := githubclient.NewClient(&cfg.GitHub, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	return client, nil
+}
+
 // createApp creates application instance
 func createApp(cfg *config.Config, log logger.Logger) (*App, error) {
-	// Create GitHub client
-	githubClient := githubclient.NewClient(&cfg.GitHub, log)
-	issueProcessor := githubclient.NewIssueProcessor(githubClient, &cfg.GitHub, log)
+	// Build log links in comments point to GitHub Actions runs, which other sources don't have
+	if !cfg.Source.IsGitHub() {
+		cfg.GitHub.RunID = ""
+	}
 
-	// Create Docker builder configuration
-	builderConfig := createBuilderConfig(cfg)
-	dockerBuilder := docker.NewBuilder(builderConfig, log)
-	imageTransformer := docker.NewImageTransformer(cfg.Rules, log)
+	// Create the issue backend that image requests are read from
+	requestSource, err := createRequestSource(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	issueProcessor := githubclient.NewIssueProcessor(requestSource, &cfg.GitHub, log)
+	// Without the mapping index nothing would stop a requester from overwriting another image's target
+	issueProcessor.SetTargetOverride(cfg.Mapping.Backend != "")
+
+	// Create Docker builder (not needed in dry-run mode, which never pushes)
+	var dockerBuilder docker.Builder
+	if !cfg.App.DryRun {
+		builderConfig := createBuilderConfig(cfg)
+		dockerBuilder = docker.NewBuilder(builderConfig, log)
+	}
+	imageTransformer := docker.NewImageTransformer(cfg.Rules, cfg.RulesMode, log)
+	imageTransformer.SetDestinations(createDestinationTargets(cfg))
+	imageTransformer.SetRedirects(cfg.Redirects)
 
 	// Create registry manager factory
 	registryFactory := registry.NewRegistryManagerFactory(cfg, log)
+	registryClient := createRegistryClient(cfg, log)
+	verifier := registry.NewVerifier(registryClient, log)
+
+	mappingIndex, err := createMappingIndex(cfg, registryClient)
+	if err != nil {
+		return nil, err
+	}
+	if mappingIndex != nil {
+		imageTransformer.SetMappingIndex(mappingIndex)
+	}
 
 	// Create sync service
 	syncService := service.NewSyncService(
 		cfg,
-		githubClient,
+		requestSource,
 		issueProcessor,
 		dockerBuilder,
 		imageTransformer,
 		registryFactory,
+		registryClient,
+		verifier,
+		mappingIndex,
 		log,
 	)
 
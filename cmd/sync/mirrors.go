@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sync-image/internal/config"
+	"sync-image/internal/docker"
+	"sync-image/internal/mirrorconfig"
+	"sync-image/pkg/logger"
+)
+
+// runMirrors generates runtime mirror configuration from the transformation rules and target registries
+func runMirrors(cfg *config.Config, log logger.Logger) error {
+	if err := config.ValidateTransformConfig(cfg); err != nil {
+		return fmt.Errorf("invalid rules configuration: %w", err)
+	}
+
+	transformer := docker.NewImageTransformer(cfg.Rules, cfg.RulesMode, log)
+	transformer.SetDestinations(createDestinationTargets(cfg))
+	defaultTarget := docker.TargetFromConfig(cfg.GetEffectiveGenericConfig())
+
+	mirrors, warnings := mirrorconfig.Resolve(transformer, defaultTarget, cfg.Rules, *mirrorsHosts)
+
+	var files map[string][]byte
+	switch *mirrorsFormat {
+	case "containerd":
+		files = mirrorconfig.ContainerdHosts(mirrors)
+	case "crio":
+		files = map[string][]byte{
+			filepath.Join("registries.conf.d", "sync-image.conf"): mirrorconfig.RegistriesConf(mirrors),
+		}
+	case "docker":
+		// Merge into an existing daemon.json instead of replacing other settings
+		var existing []byte
+		if *mirrorsOutput != "" {
+			data, err := os.ReadFile(filepath.Join(*mirrorsOutput, "daemon.json"))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			existing = data
+		}
+		data, dockerWarnings, err := mirrorconfig.DockerDaemon(mirrors, existing)
+		warnings = append(warnings, dockerWarnings...)
+		if err != nil {
+			return err
+		}
+		files = map[string][]byte{"daemon.json": data}
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", warning)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if *mirrorsOutput == "" {
+		for _, name := range names {
+			fmt.Printf("# %s\n%s\n", name, files[name])
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		path := filepath.Join(*mirrorsOutput, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, files[name], 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	}
+	return nil
+}
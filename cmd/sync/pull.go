@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"sync-image/internal/config"
+	"sync-image/internal/docker"
+	"sync-image/internal/puller"
+	"sync-image/pkg/logger"
+	"sync-image/pkg/utils"
+)
+
+// runPull pulls the mirrored copy of an upstream image with a local container runtime
+// and tags it with the upstream name, so that manifests referencing the upstream image keep working
+func runPull(ctx context.Context, cfg *config.Config, log logger.Logger) error {
+	if err := config.ValidateTransformConfig(cfg); err != nil {
+		return fmt.Errorf("invalid rules configuration: %w", err)
+	}
+
+	// Use the same rules and destinations as the sync, so the mirrored name always matches
+	transformer := docker.NewImageTransformer(cfg.Rules, cfg.RulesMode, log)
+	transformer.SetDestinations(createDestinationTargets(cfg))
+
+	result, err := transformer.Transform(*pullImage, docker.TargetFromConfig(cfg.GetEffectiveGenericConfig()))
+	if err != nil {
+		return err
+	}
+	if err := transformer.ValidateTransformation(result.SourceImage, result.TargetImage); err != nil {
+		return err
+	}
+
+	if ref, err := utils.ParseReference(result.SourceImage); err == nil && ref.Digest != "" {
+		log.Warn("Digest %s is not kept by the mirror, pulling by tag instead", ref.Digest)
+	}
+
+	mirrorImage, err := puller.FullyQualified(result.TargetImage)
+	if err != nil {
+		return fmt.Errorf("invalid mirrored image %s: %w", result.TargetImage, err)
+	}
+	upstreamImage, err := puller.FullyQualified(result.SourceImage)
+	if err != nil {
+		return fmt.Errorf("invalid upstream image %s: %w", result.SourceImage, err)
+	}
+
+	runtime := *pullRuntime
+	if !*pullDryRun || runtime == puller.RuntimeAuto {
+		if runtime, err = puller.Detect(runtime); err != nil {
+			return err
+		}
+	}
+
+	commands, err := puller.Commands(runtime, *pullNamespace, mirrorImage, upstreamImage)
+	if err != nil {
+		return err
+	}
+
+	if *pullDryRun {
+		for _, command := range commands {
+			fmt.Println(command)
+		}
+		return nil
+	}
+
+	if err := puller.Run(ctx, commands, os.Stdout, log); err != nil {
+		return err
+	}
+
+	log.Info("Pulled %s as %s using %s", mirrorImage, upstreamImage, runtime)
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"sync-image/internal/config"
+	"sync-image/internal/gitops"
+	"sync-image/internal/service"
+	"sync-image/pkg/logger"
+)
+
+// runReconcile syncs the images declared in the manifest that are missing or out of date at the destination
+// The plan is printed in diff style first; with --dry-run nothing is pushed
+func runReconcile(ctx context.Context, cfg *config.Config, log logger.Logger) error {
+	if err := config.ValidateTransformConfig(cfg); err != nil {
+		return fmt.Errorf("invalid rules configuration: %w", err)
+	}
+
+	manifest, err := gitops.LoadManifest(*reconcileFile, cfg.DestinationNames())
+	if err != nil {
+		return err
+	}
+	base := loadBaseManifest(cfg, log)
+
+	sha := *reconcileSHA
+	if sha == "" {
+		sha = os.Getenv("GITHUB_SHA")
+	}
+	if *reconcileCheck {
+		if !cfg.Source.IsGitHub() {
+			return fmt.Errorf("check runs are only supported with the github request source")
+		}
+		if sha == "" {
+			return fmt.Errorf("--sha (GITHUB_SHA) is required to publish a check run")
+		}
+		if cfg.GitHub.User == "" || cfg.GitHub.Repo == "" || (cfg.GitHub.Token == "" && cfg.GitHub.AppID == 0) {
+			return fmt.Errorf("GitHub user, repo and token (or GitHub App) are required to publish a check run")
+		}
+	}
+
+	if *reconcileDryRun {
+		cfg.App.DryRun = true
+	}
+	app, err := createApp(cfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to create application instance: %w", err)
+	}
+	defer func() {
+		if err := app.syncService.Cleanup(); err != nil {
+			log.Error("Failed to cleanup resources: %v", err)
+		}
+	}()
+
+	plan, err := app.syncService.PlanReconcile(ctx, manifest, base)
+	if err != nil {
+		return err
+	}
+	fmt.Print(plan.Render())
+
+	// Nothing to push: only planning errors decide the exit status
+	if cfg.App.DryRun || len(plan.Pending()) == 0 {
+		publishCheck(ctx, app.syncService, plan, sha, !cfg.App.DryRun, log)
+		if failed := plan.Failed(); failed > 0 {
+			return fmt.Errorf("%d images in the manifest could not be planned", failed)
+		}
+		if cfg.App.DryRun {
+			log.Info("Dry-run completed, nothing was pushed")
+		} else {
+			log.Info("All images are up to date")
+		}
+		return nil
+	}
+
+	err = app.syncService.ApplyReconcile(ctx, plan)
+	fmt.Println()
+	fmt.Print(plan.RenderResult())
+	publishCheck(ctx, app.syncService, plan, sha, true, log)
+	return err
+}
+
+// loadBaseManifest loads the --base manifest; a missing or empty file means the manifest is new
+// An invalid base (e.g. referencing a destination the change removes) only disables the removal plan
+func loadBaseManifest(cfg *config.Config, log logger.Logger) *gitops.Manifest {
+	if *reconcileBase == "" {
+		return nil
+	}
+	if info, err := os.Stat(*reconcileBase); err != nil || info.Size() == 0 {
+		log.Info("Base manifest %s is missing or empty, nothing is planned as removed", *reconcileBase)
+		return nil
+	}
+
+	base, err := gitops.LoadManifest(*reconcileBase, cfg.DestinationNames())
+	if err != nil {
+		log.Warn("Ignoring base manifest, removed images are not planned: %v", err)
+		return nil
+	}
+	return base
+}
+
+// publishCheck publishes the plan or the sync results as a check run when --check-run is set
+// Failing to publish is only logged, the exit status reflects the sync itself
+func publishCheck(ctx context.Context, svc service.SyncService, plan *service.ReconcilePlan, sha string, applied bool, log logger.Logger) {
+	if !*reconcileCheck {
+		return
+	}
+	if err := svc.PublishReconcileCheck(ctx, plan, sha, applied); err != nil {
+		log.Warn("Failed to publish check run: %v", err)
+	}
+}
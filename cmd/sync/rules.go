@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"sync-image/internal/bundle"
+	"sync-image/internal/config"
+	"sync-image/internal/docker"
+	"sync-image/internal/mapping"
+	"sync-image/pkg/logger"
+)
+
+// ruleTestResult is the outcome of transforming a single image reference
+type ruleTestResult struct {
+	Image       string   `json:"image"`
+	Source      string   `json:"source,omitempty"`
+	Upstream    string   `json:"upstream,omitempty"`
+	Rules       []string `json:"rules"`
+	Destination string   `json:"destination,omitempty"`
+	Target      string   `json:"target,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// runRulesTest evaluates the transformation rules against image references without Docker or GitHub
+func runRulesTest(ctx context.Context, cfg *config.Config, log logger.Logger) error {
+	images := append([]string{}, *rulesTestImages...)
+	if *rulesTestFile != "" {
+		list, err := bundle.ReadImageList(*rulesTestFile)
+		if err != nil {
+			return err
+		}
+		images = append(images, list...)
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no images given, pass image references or --file")
+	}
+
+	if err := config.ValidateTransformConfig(cfg); err != nil {
+		return fmt.Errorf("invalid rules configuration: %w", err)
+	}
+
+	// Collisions are checked against a copy of the mapping index, so nothing is written
+	seed, err := loadMappingSeed(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	index := mapping.NewIndex(mapping.NewMemoryStore(seed))
+	index.SetRedirects(cfg.Redirects)
+	if err := index.Load(ctx); err != nil {
+		return err
+	}
+
+	transformer := docker.NewImageTransformer(cfg.Rules, cfg.RulesMode, log)
+	transformer.SetDestinations(createDestinationTargets(cfg))
+	transformer.SetRedirects(cfg.Redirects)
+	transformer.SetMappingIndex(index)
+	defaultTarget := docker.TargetFromConfig(cfg.GetEffectiveGenericConfig())
+
+	results := make([]ruleTestResult, 0, len(images))
+	failed := 0
+	for _, image := range images {
+		result := testImage(ctx, transformer, index, image, defaultTarget)
+		if result.Error != "" {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if *rulesTestOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		writeRulesTable(os.Stdout, results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d images failed", failed, len(results))
+	}
+	return nil
+}
+
+// testImage transforms and validates one image, recording it in the in-memory index
+// so that later images in the same run that collide with it are reported
+func testImage(ctx context.Context, transformer *docker.ImageTransformer, index *mapping.Index, image string, defaultTarget docker.Target) ruleTestResult {
+	result := ruleTestResult{Image: image, Rules: []string{}}
+
+	transformed, err := transformer.Transform(image, defaultTarget)
+	if transformed != nil {
+		result.Source = transformed.SourceImage
+		result.Target = transformed.TargetImage
+		result.Destination = transformed.Destination
+		if transformed.Redirected() {
+			result.Upstream = transformed.UpstreamImage
+		}
+		if transformed.Rules != nil {
+			result.Rules = transformed.Rules
+		}
+	}
+	if err == nil {
+		err = transformer.ValidateTransformation(result.Source, result.Target)
+	}
+	if err == nil {
+		err = index.Record(ctx, result.Source, result.Target)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// loadMappingSeed reads the mapping index for collision checks; only the file backend is read offline
+func loadMappingSeed(ctx context.Context, cfg *config.Config) (*mapping.Document, error) {
+	if cfg.Mapping.Backend != "file" {
+		return nil, nil
+	}
+
+	doc, err := mapping.NewFileStore(cfg.Mapping.File).Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping index %s: %w", cfg.Mapping.File, err)
+	}
+	return doc, nil
+}
+
+// writeRulesTable prints the results as an aligned table
+func writeRulesTable(out io.Writer, results []ruleTestResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tSOURCE\tRULES\tDESTINATION\tTARGET\tRESULT")
+	for _, r := range results {
+		status := "ok"
+		if r.Error != "" {
+			status = "FAIL: " + r.Error
+		}
+		source := r.Source
+		if r.Upstream != "" {
+			source += " (pulled from " + r.Upstream + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Image, dash(source), dash(strings.Join(r.Rules, ", ")), dash(r.Destination), dash(r.Target), status)
+	}
+	w.Flush()
+}
+
+// dash returns "-" for empty table cells
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"sync-image/internal/config"
+	"sync-image/internal/service"
+	"sync-image/internal/webhook"
+	"sync-image/pkg/logger"
+)
+
+// serverShutdownTimeout is how long in-flight webhook requests get to finish on shutdown
+const serverShutdownTimeout = 10 * time.Second
+
+// runServe receives GitHub webhooks and processes the queued jobs until SIGINT or SIGTERM
+// The current job is finished before exiting, queued jobs are dropped and picked up by the next sync
+func runServe(ctx context.Context, cfg *config.Config, log logger.Logger) error {
+	if *serveListen != "" {
+		cfg.Server.Listen = *serveListen
+	}
+	if !cfg.Source.IsGitHub() {
+		return fmt.Errorf("serve mode only receives GitHub webhooks, run the sync command on a schedule for source.type %s", cfg.Source.Type)
+	}
+	if cfg.Server.WebhookSecret == "" {
+		return fmt.Errorf("server.webhook_secret (WEBHOOK_SECRET) is required to verify webhook signatures")
+	}
+	if cfg.App.DryRun {
+		return fmt.Errorf("dry-run is not supported in serve mode")
+	}
+
+	app, err := createApp(cfg, log)
+	if err != nil {
+		return fmt.Errorf("failed to create application instance: %w", err)
+	}
+	services := newRepositoryServices(cfg, app, log)
+	defer services.cleanup()
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := webhook.NewServer(services.get, cfg, log)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Run(ctx)
+	}()
+
+	httpServer := &http.Server{
+		Addr:              cfg.Server.Listen,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("Listening for GitHub webhooks on %s/webhook", cfg.Server.Listen)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("webhook server failed: %w", err)
+		}
+	case <-ctx.Done():
+		log.Info("Shutting down webhook server")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Warn("Failed to shut down webhook server: %v", err)
+	}
+
+	stop()
+	<-done
+	return nil
+}
+
+// repositoryServices hands out one sync service per repository the webhook events come from.
+// With a token only GITHUB_USER/GITHUB_REPO is served. With GitHub App auth every repository the
+// App is installed on gets its own service on first use, authenticated with that installation's token.
+type repositoryServices struct {
+	cfg      *config.Config
+	log      logger.Logger
+	mu       sync.Mutex
+	services map[string]service.SyncService // lower-cased owner/repo -> service
+}
+
+// newRepositoryServices registers app as the service for the configured repository
+func newRepositoryServices(cfg *config.Config, app *App, log logger.Logger) *repositoryServices {
+	return &repositoryServices{
+		cfg: cfg,
+		log: log,
+		services: map[string]service.SyncService{
+			strings.ToLower(cfg.GitHub.User + "/" + cfg.GitHub.Repo): app.syncService,
+		},
+	}
+}
+
+// get implements webhook.ServiceFactory
+func (r *repositoryServices) get(owner, repo string) (service.SyncService, error) {
+	key := strings.ToLower(owner + "/" + repo)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if svc, ok := r.services[key]; ok {
+		return svc, nil
+	}
+	if r.cfg.GitHub.AppID == 0 {
+		return nil, nil
+	}
+
+	// Everything but the repository is shared; the copy keeps each service's issue processor on its own repository
+	repoCfg := *r.cfg
+	repoCfg.GitHub.User, repoCfg.GitHub.Repo = owner, repo
+	app, err := createApp(&repoCfg, r.log)
+	if err != nil {
+		return nil, err
+	}
+	r.log.Info("Serving webhooks for %s/%s with GitHub App %d", owner, repo, r.cfg.GitHub.AppID)
+	r.services[key] = app.syncService
+	return app.syncService, nil
+}
+
+// cleanup releases the resources of every service
+func (r *repositoryServices) cleanup() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, svc := range r.services {
+		if err := svc.Cleanup(); err != nil {
+			r.log.Error("Failed to cleanup resources for %s: %v", key, err)
+		}
+	}
+}
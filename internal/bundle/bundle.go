@@ -0,0 +1,124 @@
+// Package bundle 实现离线镜像包的导出与导入
+//
+// 镜像包是一个 tar 归档，包含描述镜像引用、摘要和 blob 的索引文件 index.json，
+// 以及按摘要存放的清单和 blob（blobs/sha256/<hex>）。导出时可以指定上一次导出的
+// 索引文件作为基线，只打包基线中不存在的 blob，用于向离线环境增量传输镜像。
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// IndexVersion 当前索引文件格式版本
+const IndexVersion = 1
+
+// IndexFileName 镜像包内索引文件名
+const IndexFileName = "index.json"
+
+// Index 镜像包索引
+type Index struct {
+	Version int         `json:"version"`
+	Created time.Time   `json:"created"`
+	Base    string      `json:"base,omitempty"` // 增量导出时使用的基线索引
+	Images  []ImageItem `json:"images"`
+	Blobs   []BlobItem  `json:"blobs"`
+}
+
+// ImageItem 镜像包中的单个镜像
+type ImageItem struct {
+	Reference string   `json:"reference"` // 标准化后的上游镜像引用
+	Digest    string   `json:"digest"`    // 顶层清单摘要
+	MediaType string   `json:"mediaType"` // 顶层清单媒体类型
+	Platforms []string `json:"platforms,omitempty"`
+	Manifests []string `json:"manifests"` // 清单摘要，子清单在前，顶层清单在最后
+	Blobs     []string `json:"blobs"`     // config 和 layer 摘要
+}
+
+// BlobItem 镜像包中的单个 blob 或清单
+type BlobItem struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	MediaType string `json:"mediaType"`
+	Included  bool   `json:"included"`         // 为 false 时内容在基线镜像包中
+	Source    string `json:"source,omitempty"` // 打包该 blob 的镜像引用，导入时据此找到已包含该 blob 的目标仓库
+}
+
+// LoadIndex 从文件读取索引
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取索引文件失败: %w", err)
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("解析索引文件失败: %w", err)
+	}
+	if index.Version != IndexVersion {
+		return nil, fmt.Errorf("不支持的索引版本: %d", index.Version)
+	}
+
+	return &index, nil
+}
+
+// Save 将索引写入文件
+func (i *Index) Save(path string) error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("编码索引失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入索引文件失败: %w", err)
+	}
+	return nil
+}
+
+// KnownBlobs 返回索引中记录的所有 blob（包括基线中的），按摘要索引
+func (i *Index) KnownBlobs() map[string]BlobItem {
+	known := make(map[string]BlobItem, len(i.Blobs))
+	for _, blob := range i.Blobs {
+		known[blob.Digest] = blob
+	}
+	return known
+}
+
+// findBlob 根据摘要查找 blob
+func (i *Index) findBlob(digest string) (BlobItem, bool) {
+	for _, blob := range i.Blobs {
+		if blob.Digest == digest {
+			return blob, true
+		}
+	}
+	return BlobItem{}, false
+}
+
+// blobPath 返回 blob 在镜像包中的路径
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+// ReadImageList 读取镜像列表文件，忽略空行和 # 开头的注释
+func ReadImageList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取镜像列表失败: %w", err)
+	}
+
+	var images []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		images = append(images, line)
+	}
+
+	if len(images) == 0 {
+		return nil, fmt.Errorf("镜像列表为空: %s", path)
+	}
+	return images, nil
+}
@@ -0,0 +1,237 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sync-image/internal/registry"
+	"sync-image/pkg/logger"
+	"sync-image/pkg/utils"
+)
+
+// Exporter 镜像包导出器
+type Exporter struct {
+	client    *registry.Client
+	parser    *utils.ImageNameParser
+	platforms []string
+	logger    logger.Logger
+}
+
+// NewExporter 创建新的镜像包导出器，platforms 为空时导出全部平台
+func NewExporter(client *registry.Client, platforms []string, log logger.Logger) *Exporter {
+	return &Exporter{
+		client:    client,
+		parser:    utils.NewImageNameParser(nil, false),
+		platforms: platforms,
+		logger:    log,
+	}
+}
+
+// exportState 单次导出过程中的状态
+type exportState struct {
+	tw      *tar.Writer
+	index   *Index
+	base    map[string]BlobItem
+	written map[string]bool
+}
+
+// Export 导出镜像到 output 指定的 tar 包，base 不为空时只打包基线中不存在的 blob
+// 导出失败时删除不完整的镜像包
+func (e *Exporter) Export(ctx context.Context, images []string, output string, base *Index) (index *Index, err error) {
+	file, err := os.Create(output)
+	if err != nil {
+		return nil, fmt.Errorf("创建镜像包失败: %w", err)
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+
+	state := &exportState{
+		tw: tar.NewWriter(file),
+		index: &Index{
+			Version: IndexVersion,
+			Created: time.Now().UTC(),
+		},
+		base:    make(map[string]BlobItem),
+		written: make(map[string]bool),
+	}
+	if base != nil {
+		state.base = base.KnownBlobs()
+		state.index.Base = base.Created.Format(time.RFC3339)
+	}
+
+	for _, image := range images {
+		item, err := e.exportImage(ctx, state, image)
+		if err != nil {
+			return nil, fmt.Errorf("导出镜像 %s 失败: %w", image, err)
+		}
+		state.index.Images = append(state.index.Images, *item)
+		e.logger.Info("已导出镜像: %s (%s)", item.Reference, item.Digest)
+	}
+
+	indexData, err := json.MarshalIndent(state.index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("编码索引失败: %w", err)
+	}
+	if err := writeEntry(state.tw, IndexFileName, int64(len(indexData)), bytes.NewReader(indexData)); err != nil {
+		return nil, err
+	}
+	if err := state.tw.Close(); err != nil {
+		return nil, fmt.Errorf("关闭镜像包失败: %w", err)
+	}
+
+	return state.index, nil
+}
+
+// exportImage 导出单个镜像的清单和 blob
+func (e *Exporter) exportImage(ctx context.Context, state *exportState, image string) (*ImageItem, error) {
+	normalized := e.parser.NormalizeImageName(image)
+	ref, err := registry.ParseImageReference(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	top, err := e.client.GetManifest(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("获取清单失败: %w", err)
+	}
+	manifest, err := registry.ParseManifest(top.Data, top.MediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &ImageItem{
+		Reference: ref.String(),
+		MediaType: manifest.MediaType,
+	}
+
+	topData := top.Data
+	if manifest.IsIndex() {
+		children := manifest.Manifests
+		if len(e.platforms) > 0 {
+			if topData, children, err = registry.FilterIndex(top.Data, e.platforms); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, child := range children {
+			childRef := ref
+			childRef.Reference = child.Digest
+			resp, err := e.client.GetManifest(ctx, childRef)
+			if err != nil {
+				return nil, fmt.Errorf("获取子清单 %s 失败: %w", child.Digest, err)
+			}
+			childManifest, err := registry.ParseManifest(resp.Data, child.MediaType)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.exportBlobs(ctx, state, ref, item, childManifest); err != nil {
+				return nil, err
+			}
+			if err := state.writeManifest(child.Digest, childManifest.MediaType, resp.Data); err != nil {
+				return nil, err
+			}
+			item.Manifests = append(item.Manifests, child.Digest)
+			item.Platforms = append(item.Platforms, child.Platform.String())
+		}
+	} else if err := e.exportBlobs(ctx, state, ref, item, manifest); err != nil {
+		return nil, err
+	}
+
+	item.Digest = registry.Digest(topData)
+	if err := state.writeManifest(item.Digest, manifest.MediaType, topData); err != nil {
+		return nil, err
+	}
+	item.Manifests = append(item.Manifests, item.Digest)
+
+	return item, nil
+}
+
+// exportBlobs 导出单架构清单引用的 blob
+func (e *Exporter) exportBlobs(ctx context.Context, state *exportState, ref registry.ImageReference, item *ImageItem, manifest *registry.Manifest) error {
+	for _, blob := range manifest.BlobDescriptors() {
+		item.Blobs = append(item.Blobs, blob.Digest)
+
+		if state.written[blob.Digest] {
+			continue
+		}
+		state.written[blob.Digest] = true
+
+		entry := BlobItem{
+			Digest:    blob.Digest,
+			Size:      blob.Size,
+			MediaType: blob.MediaType,
+			Included:  true,
+			Source:    item.Reference,
+		}
+		if known, ok := state.base[blob.Digest]; ok {
+			// 沿用基线中的来源，导入时从基线镜像所在的目标仓库挂载
+			entry.Included, entry.Source = false, known.Source
+		}
+		state.index.Blobs = append(state.index.Blobs, entry)
+
+		if !entry.Included {
+			e.logger.Debug("blob 已存在于基线镜像包中，跳过: %s", blob.Digest)
+			continue
+		}
+
+		e.logger.Debug("导出 blob: %s (%d bytes)", blob.Digest, blob.Size)
+		reader, err := e.client.GetBlob(ctx, ref, blob.Digest)
+		if err != nil {
+			return fmt.Errorf("下载 blob %s 失败: %w", blob.Digest, err)
+		}
+		err = writeEntry(state.tw, blobPath(blob.Digest), blob.Size, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeManifest 将清单写入镜像包（清单总是包含在镜像包中）
+func (s *exportState) writeManifest(digest, mediaType string, data []byte) error {
+	if s.written[digest] {
+		return nil
+	}
+	s.written[digest] = true
+
+	s.index.Blobs = append(s.index.Blobs, BlobItem{
+		Digest:    digest,
+		Size:      int64(len(data)),
+		MediaType: mediaType,
+		Included:  true,
+	})
+	return writeEntry(s.tw, blobPath(digest), int64(len(data)), bytes.NewReader(data))
+}
+
+// writeEntry 向 tar 包写入一个文件
+func writeEntry(tw *tar.Writer, name string, size int64, content io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Size:    size,
+		Mode:    0644,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入 %s 头失败: %w", name, err)
+	}
+
+	written, err := io.Copy(tw, content)
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", name, err)
+	}
+	if written != size {
+		return fmt.Errorf("写入 %s 大小不一致: 期望 %d, 实际 %d", name, size, written)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"sync-image/internal/registry"
+	"sync-image/pkg/logger"
+)
+
+// NameFunc 根据上游镜像引用计算导入目标镜像名称
+type NameFunc func(reference string) (string, error)
+
+// Importer 镜像包导入器
+type Importer struct {
+	client *registry.Client
+	logger logger.Logger
+}
+
+// NewImporter 创建新的镜像包导入器
+func NewImporter(client *registry.Client, log logger.Logger) *Importer {
+	return &Importer{
+		client: client,
+		logger: log,
+	}
+}
+
+// entry 镜像包内文件在归档中的位置
+type entry struct {
+	offset int64
+	size   int64
+}
+
+// Import 将镜像包中的镜像推送到 targetName 计算出的目标仓库
+func (i *Importer) Import(ctx context.Context, bundlePath string, targetName NameFunc) (*Index, error) {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开镜像包失败: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := scanEntries(file)
+	if err != nil {
+		return nil, err
+	}
+
+	indexEntry, ok := entries[IndexFileName]
+	if !ok {
+		return nil, fmt.Errorf("镜像包中缺少 %s", IndexFileName)
+	}
+	var index Index
+	if err := json.NewDecoder(io.NewSectionReader(file, indexEntry.offset, indexEntry.size)).Decode(&index); err != nil {
+		return nil, fmt.Errorf("解析镜像包索引失败: %w", err)
+	}
+	if index.Version != IndexVersion {
+		return nil, fmt.Errorf("不支持的索引版本: %d", index.Version)
+	}
+
+	for _, image := range index.Images {
+		target, err := targetName(image.Reference)
+		if err != nil {
+			return nil, fmt.Errorf("计算镜像 %s 的目标名称失败: %w", image.Reference, err)
+		}
+		targetRef, err := registry.ParseImageReference(target)
+		if err != nil {
+			return nil, err
+		}
+
+		i.logger.Info("导入镜像: %s -> %s", image.Reference, targetRef)
+		if err := i.importImage(ctx, file, entries, &index, image, targetRef, targetName); err != nil {
+			return nil, fmt.Errorf("导入镜像 %s 失败: %w", image.Reference, err)
+		}
+	}
+
+	return &index, nil
+}
+
+// importImage 推送单个镜像的 blob 和清单
+// 目标仓库中不存在的 blob 先尝试从打包该 blob 的镜像所在的目标仓库挂载，失败时再从镜像包上传
+func (i *Importer) importImage(ctx context.Context, file *os.File, entries map[string]entry, index *Index, image ImageItem, target registry.ImageReference, targetName NameFunc) error {
+	pushed := make(map[string]bool)
+	for _, digest := range image.Blobs {
+		if pushed[digest] {
+			continue
+		}
+		pushed[digest] = true
+
+		exists, err := i.client.BlobExists(ctx, target, digest)
+		if err != nil {
+			return fmt.Errorf("检查 blob %s 失败: %w", digest, err)
+		}
+		if exists {
+			i.logger.Debug("目标仓库已存在 blob，跳过: %s", digest)
+			continue
+		}
+
+		mounted, err := i.mountBlob(ctx, index, digest, target, targetName)
+		if err != nil {
+			return err
+		}
+		if mounted {
+			continue
+		}
+
+		e, ok := entries[blobPath(digest)]
+		if !ok {
+			return fmt.Errorf("blob %s 不在镜像包中且无法从其他目标仓库挂载，请先导入基线镜像包", digest)
+		}
+
+		i.logger.Debug("推送 blob: %s (%d bytes)", digest, e.size)
+		if err := i.client.PushBlob(ctx, target, digest, e.size, io.NewSectionReader(file, e.offset, e.size)); err != nil {
+			return err
+		}
+	}
+
+	// 子清单按摘要推送，顶层清单按目标标签推送
+	for n, digest := range image.Manifests {
+		blob, ok := index.findBlob(digest)
+		if !ok {
+			return fmt.Errorf("索引中缺少清单 %s", digest)
+		}
+		e, ok := entries[blobPath(digest)]
+		if !ok {
+			return fmt.Errorf("镜像包中缺少清单 %s", digest)
+		}
+
+		data := make([]byte, e.size)
+		if _, err := file.ReadAt(data, e.offset); err != nil {
+			return fmt.Errorf("读取清单 %s 失败: %w", digest, err)
+		}
+
+		ref := target
+		if n < len(image.Manifests)-1 {
+			ref.Reference = digest
+		}
+		if err := i.client.PutManifest(ctx, ref, blob.MediaType, data); err != nil {
+			return fmt.Errorf("推送清单 %s 失败: %w", digest, err)
+		}
+	}
+
+	return nil
+}
+
+// mountBlob 从打包该 blob 的镜像所在的目标仓库挂载 blob，索引中没有来源或来源在其他仓库域名时返回 false
+func (i *Importer) mountBlob(ctx context.Context, index *Index, digest string, target registry.ImageReference, targetName NameFunc) (bool, error) {
+	blob, ok := index.findBlob(digest)
+	if !ok || blob.Source == "" {
+		return false, nil
+	}
+	name, err := targetName(blob.Source)
+	if err != nil {
+		return false, fmt.Errorf("计算 blob %s 来源镜像 %s 的目标名称失败: %w", digest, blob.Source, err)
+	}
+	from, err := registry.ParseImageReference(name)
+	if err != nil {
+		return false, err
+	}
+	if from.Host != target.Host || from.Repository == target.Repository {
+		return false, nil
+	}
+
+	mounted, err := i.client.MountBlob(ctx, target, digest, from.Repository)
+	if err != nil {
+		i.logger.Warn("从 %s 挂载 blob %s 失败: %v", from.Repository, digest, err)
+		return false, nil
+	}
+	if mounted {
+		i.logger.Debug("已从 %s 挂载 blob: %s", from.Repository, digest)
+	}
+	return mounted, nil
+}
+
+// scanEntries 扫描 tar 包，记录每个文件内容的偏移和大小
+func scanEntries(file *os.File) (map[string]entry, error) {
+	entries := make(map[string]entry)
+	tr := tar.NewReader(file)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取镜像包失败: %w", err)
+		}
+
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("定位镜像包内容失败: %w", err)
+		}
+		entries[header.Name] = entry{offset: offset, size: header.Size}
+	}
+
+	return entries, nil
+}
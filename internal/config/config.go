@@ -3,18 +3,34 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"sync-image/pkg/utils"
 )
 
 // Config 应用程序配置结构
 type Config struct {
-	GitHub     GitHubConfig      `yaml:"github"`
-	Registries RegistriesConfig  `yaml:"registries"` // 多云配置
-	Rules      map[string]string `yaml:"rules"`
-	App        AppConfig         `yaml:"app"`
-	Platforms  string            `yaml:"platforms"` // 移到顶层配置
+	GitHub     GitHubConfig     `yaml:"github"`
+	Source     SourceConfig     `yaml:"source"`     // 搬运请求来源，默认为 github 配置的仓库
+	Registries RegistriesConfig `yaml:"registries"` // 多云配置
+	// Destinations 转换规则可以通过 destination 引用的其他目标仓库
+	Destinations map[string]DestinationConfig `yaml:"destinations"`
+	// Credentials 目标仓库引用的登录凭据
+	Credentials map[string]CredentialConfig `yaml:"credentials"`
+	Rules       RuleList                    `yaml:"rules"`      // 按优先级和书写顺序应用
+	RulesMode   string                      `yaml:"rules_mode"` // 规则应用方式: chain（默认）、first_match
+	Redirects   map[string]string           `yaml:"redirects"`  // 上游仓库域名重定向，从新域名拉取，目标名称仍按原始请求计算
+	App         AppConfig                   `yaml:"app"`
+	Platforms   string                      `yaml:"platforms"` // 移到顶层配置
+	Verify      VerifyConfig                `yaml:"verify"`
+	Mapping     MappingConfig               `yaml:"mapping"`
+	Server      ServerConfig                `yaml:"server"`
 }
 
 // GitHubConfig GitHub 相关配置
@@ -23,6 +39,65 @@ type GitHubConfig struct {
 	User  string `yaml:"user"`
 	Repo  string `yaml:"repo"`
 	RunID string `yaml:"run_id"`
+	// APIURL GitHub API 地址，为空时使用 https://api.github.com，用于 GitHub Enterprise Server
+	APIURL string `yaml:"api_url"`
+	// GitHub App 认证，配置 AppID 后代替 Token，评论以 App 的机器人身份发布
+	AppID             int64  `yaml:"app_id"`
+	AppPrivateKey     string `yaml:"app_private_key"`      // PEM 格式的私钥内容
+	AppPrivateKeyFile string `yaml:"app_private_key_file"` // 私钥文件路径，未配置私钥内容时读取
+	InstallationID    int64  `yaml:"installation_id"`      // App 在 User 账号上的安装 ID，为 0 时按仓库查找
+	// EventName 和 EventPath 为 GitHub Actions 触发事件的名称和事件文件路径，用于处理 Issue 评论命令
+	EventName string `yaml:"event_name"`
+	EventPath string `yaml:"event_path"`
+	// Limits 对提交搬运请求的用户的限制
+	Limits LimitsConfig `yaml:"limits"`
+}
+
+// 搬运请求来源类型
+const (
+	SourceGitHub = "github"
+	SourceGitLab = "gitlab"
+	SourceGitea  = "gitea" // 也适用于 Forgejo
+)
+
+// SourceConfig 搬运请求来源配置，type 为 gitlab 或 gitea 时从对应实例的仓库 Issue 读取请求
+// 结果评论中的构建链接、组织成员检查和 Check Run 仍使用 github 配置
+type SourceConfig struct {
+	Type    string `yaml:"type"`    // github（默认）、gitlab、gitea
+	URL     string `yaml:"url"`     // GitLab 或 Gitea 实例地址，如 https://gitea.example.com
+	Token   string `yaml:"token"`   // 访问令牌，GitLab 需要 api 权限，Gitea 需要 issue 读写权限
+	Project string `yaml:"project"` // 仓库路径，如 owner/repo，GitLab 可以是 group/subgroup/project
+}
+
+// IsGitHub 是否从 GitHub 仓库读取搬运请求
+func (c *SourceConfig) IsGitHub() bool {
+	return c.Type == "" || c.Type == SourceGitHub
+}
+
+// LimitsConfig 请求者限制配置，按 Issue 作者统计最近 24 小时的请求
+// 仓库所有者、组织成员和协作者不受限额、冷却时间和允许名单的限制
+type LimitsConfig struct {
+	RequestsPerDay  int           `yaml:"requests_per_day"` // 每个用户 24 小时内最多处理的请求次数，包括重试，0 表示不限制
+	BytesPerDay     string        `yaml:"bytes_per_day"`    // 每个用户 24 小时内最多同步的数据量，如 20GiB，为空表示不限制
+	FailureCooldown time.Duration `yaml:"failure_cooldown"` // 同步失败后多久内不处理该用户的新请求，如 30m，0 表示不限制
+	AllowOrgs       []string      `yaml:"allow_orgs"`       // 只接受这些组织成员的请求
+	AllowTeams      []string      `yaml:"allow_teams"`      // 只接受这些团队成员的请求，格式为 org/team-slug
+	DenyUsers       []string      `yaml:"deny_users"`       // 不接受这些用户的请求
+}
+
+// BytesLimit 返回每日数据量上限的字节数，未配置时返回 0
+func (c *LimitsConfig) BytesLimit() int64 {
+	if c.BytesPerDay == "" {
+		return 0
+	}
+	size, _ := utils.ParseBytes(c.BytesPerDay)
+	return size
+}
+
+// Enabled 是否配置了任何请求者限制
+func (c *LimitsConfig) Enabled() bool {
+	return c.RequestsPerDay > 0 || c.BytesLimit() > 0 || c.FailureCooldown > 0 ||
+		len(c.AllowOrgs) > 0 || len(c.AllowTeams) > 0 || len(c.DenyUsers) > 0
 }
 
 // RegistriesConfig 多云仓库配置
@@ -45,35 +120,113 @@ type GenericRegistryConfig struct {
 	Namespace string `yaml:"namespace"` // 命名空间
 	Username  string `yaml:"username"`  // 用户名
 	Password  string `yaml:"password"`  // 密码或访问令牌
+
+	Naming          string `yaml:"naming"`           // 目标镜像命名策略: last-segment（默认）、flatten、full-path、hash-suffix
+	NamingSeparator string `yaml:"naming_separator"` // flatten 策略的分隔符，默认 "."
 }
 
+// DestinationConfig 按规则路由的目标仓库配置
+type DestinationConfig struct {
+	Registry        string `yaml:"registry"`         // 仓库地址
+	Namespace       string `yaml:"namespace"`        // 命名空间
+	Credentials     string `yaml:"credentials"`      // 引用 credentials 中的凭据名称，为空时匿名推送
+	Naming          string `yaml:"naming"`           // 目标镜像命名策略，同 registries.generic.naming
+	NamingSeparator string `yaml:"naming_separator"` // flatten 策略的分隔符
+}
+
+// CredentialConfig 仓库登录凭据
+// 可通过环境变量 CREDENTIALS_<NAME>_USERNAME 和 CREDENTIALS_<NAME>_PASSWORD 设置
+type CredentialConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// VerifyConfig 推送后校验配置
+type VerifyConfig struct {
+	Enabled       bool   `yaml:"enabled"`        // 是否在推送后校验目标镜像
+	Anonymous     string `yaml:"anonymous"`      // 匿名拉取校验: auto（仅对会设置公开访问的仓库）、always、never
+	CompareConfig bool   `yaml:"compare_config"` // 是否比较 config 摘要
+}
+
+// MappingConfig 源仓库到目标仓库的映射索引配置
+type MappingConfig struct {
+	Backend   string `yaml:"backend"`   // 存储后端: 留空（关闭）、file、registry
+	File      string `yaml:"file"`      // file 后端的索引文件路径
+	Reference string `yaml:"reference"` // registry 后端的制品引用，默认 <registry>/<namespace>/sync-image-mapping:latest
+}
+
+// ServerConfig serve 模式的 Webhook 服务配置
+type ServerConfig struct {
+	Listen        string `yaml:"listen"`         // 监听地址，默认 :8080
+	WebhookSecret string `yaml:"webhook_secret"` // GitHub Webhook 的 Secret，用于校验 X-Hub-Signature-256
+	QueueSize     int    `yaml:"queue_size"`     // 等待处理的任务数上限，默认 100
+}
+
+// defaultMappingRepository registry 后端默认的制品仓库名
+const defaultMappingRepository = "sync-image-mapping"
+
 // AppConfig 应用程序配置
 type AppConfig struct {
-	LogLevel string `yaml:"log_level"`
-	Debug    bool   `yaml:"debug"`
+	LogLevel      string `yaml:"log_level"`
+	Debug         bool   `yaml:"debug"`
+	DryRun        bool   `yaml:"dry_run"`         // 只生成同步计划，不推送、不打标签、不关闭 Issue
+	DryRunComment bool   `yaml:"dry_run_comment"` // dry-run 时是否将同步计划评论到 Issue
+	Workers       int    `yaml:"workers"`         // 同时处理的 Issue 数量
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
 		Platforms: "linux/amd64,linux/arm64",
-		Rules: map[string]string{
-			"^gcr.io":          "",
-			"^docker.io":       "docker",
-			"^k8s.gcr.io":      "google-containers",
-			"^registry.k8s.io": "google-containers",
-			"^quay.io":         "quay",
-			"^ghcr.io":         "ghcr",
+		Rules: RuleList{
+			{Pattern: "^gcr.io", Replacement: ""},
+			{Pattern: "^docker.io", Replacement: "docker"},
+			{Pattern: "^k8s.gcr.io", Replacement: "google-containers"},
+			{Pattern: "^registry.k8s.io", Replacement: "google-containers"},
+			{Pattern: "^quay.io", Replacement: "quay"},
+			{Pattern: "^ghcr.io", Replacement: "ghcr"},
+		},
+		RulesMode: RulesModeChain,
+		Redirects: map[string]string{
+			"k8s.gcr.io": "registry.k8s.io",
 		},
 		App: AppConfig{
 			LogLevel: "info",
 			Debug:    false,
+			Workers:  2,
+		},
+		Verify: VerifyConfig{
+			Enabled:   true,
+			Anonymous: "auto",
+		},
+		Mapping: MappingConfig{
+			File: "mappings.json",
+		},
+		Server: ServerConfig{
+			Listen:    ":8080",
+			QueueSize: 100,
 		},
 	}
 }
 
 // LoadConfig 从文件和环境变量加载配置
 func LoadConfig(configPath string) (*Config, error) {
+	config, err := Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证配置
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// Load 从文件和环境变量加载配置，不做校验
+// 用于不需要 GitHub 凭据的离线命令，调用方按需校验
+func Load(configPath string) (*Config, error) {
 	config := DefaultConfig()
 
 	// 从文件加载配置
@@ -88,11 +241,6 @@ func LoadConfig(configPath string) (*Config, error) {
 
 	// 不再提供向后兼容性支持
 
-	// 验证配置
-	if err := validateConfig(config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
-	}
-
 	return config, nil
 }
 
@@ -103,19 +251,47 @@ func loadFromFile(config *Config, filePath string) error {
 		return err
 	}
 
-	// 尝试直接解析为完整配置
-	if err := yaml.Unmarshal(data, config); err == nil {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("无法解析配置文件 %s: %w", filePath, err)
+	}
+	if len(document.Content) == 0 {
 		return nil
 	}
 
-	// 如果失败，尝试只解析规则部分
-	rules := make(map[string]string)
-	if err := yaml.Unmarshal(data, &rules); err == nil {
+	// 只包含转换规则的文件（如 rules.yaml）：顶层是规则映射或规则列表，而不是完整配置
+	// 完整配置的未知字段会被忽略，因此需要先检查顶层字段，否则规则会被静默丢弃
+	if root := document.Content[0]; !isConfigDocument(root) {
+		var rules RuleList
+		if err := root.Decode(&rules); err != nil {
+			return fmt.Errorf("无法解析规则文件 %s: %w", filePath, err)
+		}
 		config.Rules = rules
 		return nil
 	}
 
-	return fmt.Errorf("无法解析配置文件: %s", filePath)
+	if err := document.Decode(config); err != nil {
+		return fmt.Errorf("无法解析配置文件 %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// isConfigDocument 检查 YAML 顶层节点是否为完整配置（包含 Config 的任一顶层字段）
+func isConfigDocument(root *yaml.Node) bool {
+	if root.Kind != yaml.MappingNode {
+		return false
+	}
+
+	fields := reflect.TypeOf(Config{})
+	for i := 0; i < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		for j := 0; j < fields.NumField(); j++ {
+			if strings.Split(fields.Field(j).Tag.Get("yaml"), ",")[0] == key {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // loadFromEnv 从环境变量加载配置
@@ -133,6 +309,63 @@ func loadFromEnv(config *Config) {
 	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
 		config.GitHub.RunID = runID
 	}
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		config.GitHub.APIURL = apiURL
+	}
+	if appID := os.Getenv("GITHUB_APP_ID"); appID != "" {
+		if id, err := strconv.ParseInt(appID, 10, 64); err == nil {
+			config.GitHub.AppID = id
+		}
+	}
+	if key := os.Getenv("GITHUB_APP_PRIVATE_KEY"); key != "" {
+		config.GitHub.AppPrivateKey = key
+	}
+	if keyFile := os.Getenv("GITHUB_APP_PRIVATE_KEY_FILE"); keyFile != "" {
+		config.GitHub.AppPrivateKeyFile = keyFile
+	}
+	if installationID := os.Getenv("GITHUB_APP_INSTALLATION_ID"); installationID != "" {
+		if id, err := strconv.ParseInt(installationID, 10, 64); err == nil {
+			config.GitHub.InstallationID = id
+		}
+	}
+	if eventName := os.Getenv("GITHUB_EVENT_NAME"); eventName != "" {
+		config.GitHub.EventName = eventName
+	}
+	if eventPath := os.Getenv("GITHUB_EVENT_PATH"); eventPath != "" {
+		config.GitHub.EventPath = eventPath
+	}
+
+	// 搬运请求来源配置
+	if sourceType := os.Getenv("SOURCE_TYPE"); sourceType != "" {
+		config.Source.Type = sourceType
+	}
+	if sourceURL := os.Getenv("SOURCE_URL"); sourceURL != "" {
+		config.Source.URL = sourceURL
+	}
+	if token := os.Getenv("SOURCE_TOKEN"); token != "" {
+		config.Source.Token = token
+	}
+	if project := os.Getenv("SOURCE_PROJECT"); project != "" {
+		config.Source.Project = project
+	}
+
+	// 请求者限制配置
+	if requests := os.Getenv("LIMITS_REQUESTS_PER_DAY"); requests != "" {
+		if n, err := strconv.Atoi(requests); err == nil {
+			config.GitHub.Limits.RequestsPerDay = n
+		}
+	}
+	if bytes := os.Getenv("LIMITS_BYTES_PER_DAY"); bytes != "" {
+		config.GitHub.Limits.BytesPerDay = bytes
+	}
+	if cooldown := os.Getenv("LIMITS_FAILURE_COOLDOWN"); cooldown != "" {
+		if d, err := time.ParseDuration(cooldown); err == nil {
+			config.GitHub.Limits.FailureCooldown = d
+		}
+	}
+	if users := os.Getenv("LIMITS_DENY_USERS"); users != "" {
+		config.GitHub.Limits.DenyUsers = strings.Split(users, ",")
+	}
 
 	// 平台架构配置
 	if platforms := os.Getenv("PLATFORMS"); platforms != "" {
@@ -169,6 +402,56 @@ func loadFromEnv(config *Config) {
 	if debug := os.Getenv("DEBUG"); debug != "" {
 		config.App.Debug = strings.ToLower(debug) == "true"
 	}
+	if workers := os.Getenv("WORKERS"); workers != "" {
+		if n, err := strconv.Atoi(workers); err == nil {
+			config.App.Workers = n
+		}
+	}
+
+	// 推送后校验配置
+	if verify := os.Getenv("VERIFY_ENABLED"); verify != "" {
+		config.Verify.Enabled = strings.ToLower(verify) == "true"
+	}
+
+	// 目标仓库凭据，名称中的 "-" 和 "." 在环境变量中替换为 "_"
+	for _, destination := range config.Destinations {
+		if destination.Credentials == "" {
+			continue
+		}
+		prefix := "CREDENTIALS_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(destination.Credentials))
+		credential := config.Credentials[destination.Credentials]
+		if username := os.Getenv(prefix + "_USERNAME"); username != "" {
+			credential.Username = username
+		}
+		if password := os.Getenv(prefix + "_PASSWORD"); password != "" {
+			credential.Password = password
+		}
+		if credential != (CredentialConfig{}) {
+			if config.Credentials == nil {
+				config.Credentials = make(map[string]CredentialConfig)
+			}
+			config.Credentials[destination.Credentials] = credential
+		}
+	}
+
+	// 映射索引配置
+	if backend := os.Getenv("MAPPING_BACKEND"); backend != "" {
+		config.Mapping.Backend = backend
+	}
+	if file := os.Getenv("MAPPING_FILE"); file != "" {
+		config.Mapping.File = file
+	}
+	if reference := os.Getenv("MAPPING_REFERENCE"); reference != "" {
+		config.Mapping.Reference = reference
+	}
+
+	// Webhook 服务配置
+	if listen := os.Getenv("SERVER_LISTEN"); listen != "" {
+		config.Server.Listen = listen
+	}
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		config.Server.WebhookSecret = secret
+	}
 }
 
 // loadRegistriesFromEnv 从环境变量加载多云仓库配置
@@ -218,20 +501,30 @@ func loadRegistriesFromEnv(config *Config) {
 		}
 		config.Registries.Generic.Password = password
 	}
+	if naming := os.Getenv("GENERIC_NAMING"); naming != "" {
+		if config.Registries.Generic == nil {
+			config.Registries.Generic = &GenericRegistryConfig{}
+		}
+		config.Registries.Generic.Naming = naming
+	}
+	if separator := os.Getenv("GENERIC_NAMING_SEPARATOR"); separator != "" {
+		if config.Registries.Generic == nil {
+			config.Registries.Generic = &GenericRegistryConfig{}
+		}
+		config.Registries.Generic.NamingSeparator = separator
+	}
 }
 
 // 不再提供向后兼容性支持
 
 // validateConfig 验证配置的有效性
 func validateConfig(config *Config) error {
-	if config.GitHub.Token == "" {
-		return fmt.Errorf("GitHub token is required")
-	}
-	if config.GitHub.User == "" {
-		return fmt.Errorf("GitHub user is required")
-	}
-	if config.GitHub.Repo == "" {
-		return fmt.Errorf("GitHub repo is required")
+	if config.Source.IsGitHub() {
+		if err := validateGitHub(&config.GitHub); err != nil {
+			return err
+		}
+	} else if err := validateSource(config); err != nil {
+		return err
 	}
 	// 所有仓库配置都是可选的，不强制要求
 
@@ -249,6 +542,176 @@ func validateConfig(config *Config) error {
 		}
 	}
 
+	if config.App.Workers < 1 {
+		return fmt.Errorf("app.workers must be at least 1")
+	}
+
+	if err := validateLimits(&config.GitHub.Limits); err != nil {
+		return err
+	}
+
+	if config.Server.QueueSize < 1 {
+		return fmt.Errorf("server.queue_size must be at least 1")
+	}
+
+	switch config.Verify.Anonymous {
+	case "", "auto", "always", "never":
+	default:
+		return fmt.Errorf("verify.anonymous must be one of auto, always, never")
+	}
+
+	if err := validateRules(config.Rules, config.RulesMode); err != nil {
+		return err
+	}
+
+	if err := validateDestinations(config); err != nil {
+		return err
+	}
+
+	if err := validateRedirects(config.Redirects); err != nil {
+		return err
+	}
+
+	switch config.Mapping.Backend {
+	case "":
+	case "file":
+		if config.Mapping.File == "" {
+			return fmt.Errorf("mapping.file is required when mapping.backend is file")
+		}
+	case "registry":
+		if config.MappingReference() == "" {
+			return fmt.Errorf("mapping.reference or generic registry config is required when mapping.backend is registry")
+		}
+	default:
+		return fmt.Errorf("mapping.backend must be one of file, registry")
+	}
+
+	return nil
+}
+
+// validateGitHub 验证从 GitHub 仓库读取搬运请求所需的配置
+func validateGitHub(github *GitHubConfig) error {
+	if github.Token == "" && github.AppID == 0 {
+		return fmt.Errorf("GitHub token is required")
+	}
+	if github.AppID != 0 && github.AppPrivateKey == "" && github.AppPrivateKeyFile == "" {
+		return fmt.Errorf("github.app_private_key or github.app_private_key_file is required when github.app_id is set")
+	}
+	if github.User == "" {
+		return fmt.Errorf("GitHub user is required")
+	}
+	if github.Repo == "" {
+		return fmt.Errorf("GitHub repo is required")
+	}
+	return nil
+}
+
+// validateSource 验证 GitLab 或 Gitea 请求来源配置
+func validateSource(config *Config) error {
+	source := &config.Source
+	switch source.Type {
+	case SourceGitLab, SourceGitea:
+	default:
+		return fmt.Errorf("source.type must be one of github, gitlab, gitea")
+	}
+	if source.URL == "" {
+		return fmt.Errorf("source.url is required when source.type is %s", source.Type)
+	}
+	if source.Token == "" {
+		return fmt.Errorf("source.token is required when source.type is %s", source.Type)
+	}
+	if owner, name, ok := strings.Cut(source.Project, "/"); !ok || owner == "" || name == "" {
+		return fmt.Errorf("source.project must be in owner/repo form")
+	}
+	if len(config.GitHub.Limits.AllowOrgs) > 0 || len(config.GitHub.Limits.AllowTeams) > 0 {
+		return fmt.Errorf("github.limits.allow_orgs and allow_teams are only supported with the github source")
+	}
+	return nil
+}
+
+// validateLimits 验证请求者限制配置
+func validateLimits(limits *LimitsConfig) error {
+	if limits.RequestsPerDay < 0 {
+		return fmt.Errorf("github.limits.requests_per_day must not be negative")
+	}
+	if limits.BytesPerDay != "" {
+		if _, err := utils.ParseBytes(limits.BytesPerDay); err != nil {
+			return fmt.Errorf("github.limits.bytes_per_day is invalid: %w", err)
+		}
+	}
+	if limits.FailureCooldown < 0 {
+		return fmt.Errorf("github.limits.failure_cooldown must not be negative")
+	}
+	for _, team := range limits.AllowTeams {
+		if org, slug, ok := strings.Cut(team, "/"); !ok || org == "" || slug == "" {
+			return fmt.Errorf("github.limits.allow_teams entry %q must be in org/team form", team)
+		}
+	}
+	return nil
+}
+
+// ValidateTransformConfig 只验证镜像名称转换相关的配置（规则、目标仓库和命名策略）
+func ValidateTransformConfig(config *Config) error {
+	if err := validateRules(config.Rules, config.RulesMode); err != nil {
+		return err
+	}
+	if generic := config.GetEffectiveGenericConfig(); generic != nil {
+		if _, err := utils.ParseNamingStrategy(generic.Naming); err != nil {
+			return err
+		}
+		if err := utils.ValidateNamingSeparator(generic.NamingSeparator); err != nil {
+			return err
+		}
+	}
+	if err := validateRedirects(config.Redirects); err != nil {
+		return err
+	}
+	return validateDestinations(config)
+}
+
+// validateRedirects 验证仓库域名重定向表
+func validateRedirects(redirects map[string]string) error {
+	for from, to := range redirects {
+		if !utils.IsValidDomain(from) {
+			return fmt.Errorf("redirects: invalid registry %q", from)
+		}
+		if to != "" && !utils.IsValidDomain(to) {
+			return fmt.Errorf("redirects: invalid registry %q for %s", to, from)
+		}
+		if to == from {
+			return fmt.Errorf("redirects: %s redirects to itself", from)
+		}
+	}
+	return nil
+}
+
+// validateDestinations 验证目标仓库配置以及规则对目标仓库的引用
+func validateDestinations(config *Config) error {
+	for name, destination := range config.Destinations {
+		if destination.Registry == "" {
+			return fmt.Errorf("destination %q: registry is required", name)
+		}
+		if destination.Credentials != "" {
+			if _, ok := config.Credentials[destination.Credentials]; !ok {
+				return fmt.Errorf("destination %q: credentials %q not found", name, destination.Credentials)
+			}
+		}
+		if _, err := utils.ParseNamingStrategy(destination.Naming); err != nil {
+			return fmt.Errorf("destination %q: %w", name, err)
+		}
+		if err := utils.ValidateNamingSeparator(destination.NamingSeparator); err != nil {
+			return fmt.Errorf("destination %q: %w", name, err)
+		}
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Destination == "" {
+			continue
+		}
+		if _, ok := config.Destinations[rule.Destination]; !ok {
+			return fmt.Errorf("rule #%d: destination %q not found", i+1, rule.Destination)
+		}
+	}
 	return nil
 }
 
@@ -275,19 +738,55 @@ func validateGenericRegistryConfig(config *GenericRegistryConfig) error {
 	}
 	// username 和 password 是可选的，支持匿名访问公共仓库
 	// namespace 是可选的，某些仓库可能不需要
+	if _, err := utils.ParseNamingStrategy(config.Naming); err != nil {
+		return err
+	}
+	if err := utils.ValidateNamingSeparator(config.NamingSeparator); err != nil {
+		return err
+	}
 	return nil
 }
 
+// TargetNaming 返回目标镜像命名方式，配置无效时回退为 last-segment
+func (c *GenericRegistryConfig) TargetNaming() utils.TargetNaming {
+	if c == nil {
+		return utils.TargetNaming{Strategy: utils.NamingLastSegment}
+	}
+
+	strategy, err := utils.ParseNamingStrategy(c.Naming)
+	if err != nil {
+		strategy = utils.NamingLastSegment
+	}
+	return utils.TargetNaming{Strategy: strategy, Separator: c.NamingSeparator}
+}
+
 // GetSafeConfig 返回脱敏后的配置用于日志记录
 func (c *Config) GetSafeConfig() *Config {
 	safe := *c
 	safe.GitHub.Token = maskSensitive(c.GitHub.Token)
+	safe.Source.Token = maskSensitive(c.Source.Token)
+	if c.GitHub.AppPrivateKey != "" {
+		safe.GitHub.AppPrivateKey = maskSensitive(c.GitHub.AppPrivateKey)
+	}
+	if c.Server.WebhookSecret != "" {
+		safe.Server.WebhookSecret = maskSensitive(c.Server.WebhookSecret)
+	}
 
 	// 华为云配置已移至 registries 配置中
 
 	// 脱敏新的多云配置
 	safe.Registries = maskRegistriesConfig(c.Registries)
 
+	if c.Credentials != nil {
+		safe.Credentials = make(map[string]CredentialConfig, len(c.Credentials))
+		for name, credential := range c.Credentials {
+			safe.Credentials[name] = CredentialConfig{
+				Username: credential.Username,
+				Password: maskSensitive(credential.Password),
+			}
+		}
+	}
+
 	return &safe
 }
 
@@ -309,6 +808,9 @@ func maskRegistriesConfig(registries RegistriesConfig) RegistriesConfig {
 			Namespace: registries.Generic.Namespace,
 			Username:  registries.Generic.Username,
 			Password:  maskSensitive(registries.Generic.Password),
+
+			Naming:          registries.Generic.Naming,
+			NamingSeparator: registries.Generic.NamingSeparator,
 		}
 	}
 
@@ -327,6 +829,71 @@ func (c *Config) GetEffectiveGenericConfig() *GenericRegistryConfig {
 	return c.Registries.Generic
 }
 
+// GetDestination 返回指定名称的目标仓库配置（已解析凭据），名称为空时返回通用仓库配置
+// 返回值与通用仓库配置使用相同的结构，便于构建、后处理和校验流程统一处理
+func (c *Config) GetDestination(name string) *GenericRegistryConfig {
+	if name == "" {
+		return c.GetEffectiveGenericConfig()
+	}
+
+	destination, ok := c.Destinations[name]
+	if !ok {
+		return nil
+	}
+
+	credential := c.Credentials[destination.Credentials]
+	return &GenericRegistryConfig{
+		Registry:        destination.Registry,
+		Namespace:       destination.Namespace,
+		Username:        credential.Username,
+		Password:        credential.Password,
+		Naming:          destination.Naming,
+		NamingSeparator: destination.NamingSeparator,
+	}
+}
+
+// DestinationNames 返回所有按规则路由的目标仓库名称，按名称排序
+func (c *Config) DestinationNames() []string {
+	names := make([]string, 0, len(c.Destinations))
+	for name := range c.Destinations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetDestinationForRegistry 返回仓库地址对应的目标仓库配置，优先匹配通用仓库配置
+func (c *Config) GetDestinationForRegistry(registryURL string) *GenericRegistryConfig {
+	generic := c.GetEffectiveGenericConfig()
+	if generic != nil && generic.Registry == registryURL {
+		return generic
+	}
+
+	for _, name := range c.DestinationNames() {
+		if c.Destinations[name].Registry == registryURL {
+			return c.GetDestination(name)
+		}
+	}
+	return generic
+}
+
+// MappingReference 返回 registry 后端的映射索引制品引用
+// 未显式配置时使用通用仓库配置的仓库地址和命名空间
+func (c *Config) MappingReference() string {
+	if c.Mapping.Reference != "" {
+		return c.Mapping.Reference
+	}
+
+	generic := c.GetEffectiveGenericConfig()
+	if generic == nil || generic.Registry == "" {
+		return ""
+	}
+	if generic.Namespace == "" {
+		return generic.Registry + "/" + defaultMappingRepository + ":latest"
+	}
+	return generic.Registry + "/" + generic.Namespace + "/" + defaultMappingRepository + ":latest"
+}
+
 // HasRegistryConfig 检查是否配置了特定类型的仓库
 func (c *Config) HasRegistryConfig(registryType string) bool {
 	switch registryType {
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"sync-image/pkg/utils"
+)
+
+// 转换规则的应用方式
+const (
+	RulesModeChain      = "chain"       // 按顺序依次应用所有匹配的规则（默认）
+	RulesModeFirstMatch = "first_match" // 只应用第一条匹配的规则
+)
+
+// Rule 镜像名称转换规则
+type Rule struct {
+	Name        string `yaml:"name"`
+	Pattern     string `yaml:"pattern"`     // 正则表达式，匹配标准化后的源镜像名称
+	Replacement string `yaml:"replacement"` // 替换内容，支持 $1、${name} 等捕获组引用
+	Priority    int    `yaml:"priority"`    // 优先级，数值越大越先应用，相同时按书写顺序
+	Description string `yaml:"description"`
+	Destination string `yaml:"destination"` // 引用 destinations 中的目标仓库，为空时使用通用仓库配置
+}
+
+// RuleList 有序的转换规则列表
+// 同时支持旧的映射格式（"pattern": "replacement"，按书写顺序）和规则对象列表格式
+type RuleList []Rule
+
+// UnmarshalYAML 实现 yaml.Unmarshaler，按文档顺序读取映射格式的规则
+func (l *RuleList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.MappingNode:
+		rules := make(RuleList, 0, len(value.Content)/2)
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key, val := value.Content[i], value.Content[i+1]
+			if val.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: rule %q must map to a string replacement", val.Line, key.Value)
+			}
+			rules = append(rules, Rule{Pattern: key.Value, Replacement: val.Value})
+		}
+		*l = rules
+		return nil
+	case yaml.SequenceNode:
+		var rules []Rule
+		if err := value.Decode(&rules); err != nil {
+			return err
+		}
+		*l = rules
+		return nil
+	default:
+		return fmt.Errorf("line %d: rules must be a mapping or a list", value.Line)
+	}
+}
+
+// Sorted 返回按优先级排序后的规则，优先级相同时保持原有顺序
+func (l RuleList) Sorted() RuleList {
+	sorted := make(RuleList, len(l))
+	copy(sorted, l)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	return sorted
+}
+
+// RewriteRules 返回按应用顺序排列的镜像名称转换规则
+func (l RuleList) RewriteRules() []utils.RewriteRule {
+	sorted := l.Sorted()
+	rules := make([]utils.RewriteRule, 0, len(sorted))
+	for _, rule := range sorted {
+		rules = append(rules, utils.RewriteRule{
+			Name:        rule.Name,
+			Pattern:     rule.Pattern,
+			Replacement: rule.Replacement,
+			Destination: rule.Destination,
+		})
+	}
+	return rules
+}
+
+// validateRules 验证转换规则和应用方式
+func validateRules(rules RuleList, mode string) error {
+	switch mode {
+	case "", RulesModeChain, RulesModeFirstMatch:
+	default:
+		return fmt.Errorf("rules_mode must be one of %s, %s", RulesModeChain, RulesModeFirstMatch)
+	}
+
+	names := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule #%d: pattern is required", i+1)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("rule #%d: invalid pattern %q: %w", i+1, rule.Pattern, err)
+		}
+		if rule.Name != "" {
+			if names[rule.Name] {
+				return fmt.Errorf("rule #%d: duplicate rule name %q", i+1, rule.Name)
+			}
+			names[rule.Name] = true
+		}
+	}
+	return nil
+}
@@ -10,32 +10,45 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 
+	"sync-image/internal/config"
+	"sync-image/internal/mapping"
 	"sync-image/pkg/errors"
 	"sync-image/pkg/logger"
 	"sync-image/pkg/utils"
 )
 
 // Builder Docker 构建器接口
+// 实现需要支持并发调用 BuildAndPush
 type Builder interface {
 	Login(ctx context.Context) error
-	BuildAndPush(ctx context.Context, sourceImage, targetImage, platform string) error
-	WriteDockerfile(sourceImage string) error
+	BuildAndPush(ctx context.Context, sourceImage, targetImage, platform string) (*BuildResult, error)
 	Cleanup() error
-	GetLastArchitectureInfo() string // 获取最后一次构建的架构信息
+}
+
+// BuildResult 单次构建的结果
+type BuildResult struct {
+	ArchitectureInfo string // 架构信息，用于结果评论
 }
 
 // SDKBuilder 使用 Docker SDK 的构建器实现
 type SDKBuilder struct {
-	client       *client.Client
-	config       *BuilderConfig
-	logger       logger.Logger
-	lastArchInfo string // 最后一次构建的架构信息
+	client *client.Client
+	config *BuilderConfig
+	logger logger.Logger
+
+	loginMu  sync.Mutex
+	loggedIn map[string]bool // 已登录的仓库，避免并发构建重复登录
+
+	builderMu    sync.Mutex
+	builderReady bool // 多平台 buildx 构建器是否已设置
 }
 
 // BuilderConfig Docker构建器配置
@@ -45,6 +58,16 @@ type BuilderConfig struct {
 	Username  string
 	Password  string
 	Platforms string
+
+	// ExtraLogins 按转换规则路由的其他目标仓库的登录凭据
+	ExtraLogins []RegistryLogin
+}
+
+// RegistryLogin 仓库登录凭据
+type RegistryLogin struct {
+	Registry string // 为空表示 Docker Hub
+	Username string
+	Password string
 }
 
 // createDockerClient 创建 Docker 客户端
@@ -86,29 +109,35 @@ func NewBuilder(cfg *BuilderConfig, log logger.Logger) Builder {
 	log.Info("使用 Docker SDK 构建器")
 	log.Info("Docker 连接测试成功")
 	return &SDKBuilder{
-		client: cli,
-		config: cfg,
-		logger: log,
+		client:   cli,
+		config:   cfg,
+		logger:   log,
+		loggedIn: make(map[string]bool),
 	}
 }
 
 // Login 登录到 Docker 注册表
 func (b *SDKBuilder) Login(ctx context.Context) error {
-	if !b.hasCredentials() {
+	return b.login(ctx, b.defaultLogin())
+}
+
+// login 使用指定凭据登录到 Docker 注册表
+func (b *SDKBuilder) login(ctx context.Context, login RegistryLogin) error {
+	if !hasCredentials(login) {
 		b.logger.Debug("跳过 Docker 登录（无凭据配置）")
 		return nil
 	}
 
-	registryAddr := b.getRegistryAddress()
+	registryAddr := getRegistryAddress(login)
 	b.logger.Debug("使用 Docker SDK 登录到注册表: `%s`", registryAddr)
 
-	authConfig := b.createAuthConfig()
+	authConfig := createAuthConfig(login)
 
 	_, err := b.client.RegistryLogin(ctx, authConfig)
 	if err != nil {
 		return errors.NewDockerError("Docker SDK 登录失败", err).
 			WithContext("registry", registryAddr).
-			WithContext("username", b.config.Username)
+			WithContext("username", login.Username)
 	}
 
 	b.logger.Info("成功使用 Docker SDK 登录到注册表")
@@ -116,12 +145,14 @@ func (b *SDKBuilder) Login(ctx context.Context) error {
 }
 
 // BuildAndPush 构建并推送镜像
-func (b *SDKBuilder) BuildAndPush(ctx context.Context, sourceImage, targetImage, platform string) error {
+// 失败时返回的结果中可能已包含架构信息
+func (b *SDKBuilder) BuildAndPush(ctx context.Context, sourceImage, targetImage, platform string) (*BuildResult, error) {
 	b.logger.Info("使用 Docker SDK 开始构建镜像: %s -> %s", sourceImage, targetImage)
+	result := &BuildResult{}
 
 	// 首先确保 Docker 登录
-	if err := b.ensureDockerLogin(ctx); err != nil {
-		return fmt.Errorf("Docker 登录失败: %w", err)
+	if err := b.ensureDockerLogin(ctx, targetImage); err != nil {
+		return result, fmt.Errorf("Docker 登录失败: %w", err)
 	}
 
 	// 检测上游镜像支持的架构
@@ -138,16 +169,17 @@ func (b *SDKBuilder) BuildAndPush(ctx context.Context, sourceImage, targetImage,
 	}
 
 	// 根据上游镜像架构和目标平台决定构建策略
-	return b.chooseBuildStrategy(ctx, sourceImage, targetImage, targetPlatforms, upstreamArchs)
+	return result, b.chooseBuildStrategy(ctx, sourceImage, targetImage, targetPlatforms, upstreamArchs, result)
 }
 
 // buildWithBuildx 使用 buildx 进行多架构构建
 func (b *SDKBuilder) buildWithBuildx(ctx context.Context, sourceImage, targetImage, platforms string) error {
-	// 创建临时 Dockerfile
-	if err := b.WriteDockerfile(sourceImage); err != nil {
+	// 每次构建使用独立的临时构建上下文，并发构建互不影响
+	contextDir, err := b.writeBuildContext(sourceImage)
+	if err != nil {
 		return err
 	}
-	defer b.cleanupDockerfile()
+	defer b.removeBuildContext(contextDir)
 
 	// 确保有可用的多平台构建器
 	if err := b.ensureMultiPlatformBuilder(); err != nil {
@@ -155,7 +187,7 @@ func (b *SDKBuilder) buildWithBuildx(ctx context.Context, sourceImage, targetIma
 	}
 
 	// 使用 buildx 命令进行多架构构建
-	return b.execBuildxCommand(targetImage, platforms)
+	return b.execBuildxCommand(contextDir, targetImage, platforms, progressFrom(ctx))
 }
 
 // buildSingleArch 单架构构建使用 SDK
@@ -268,7 +300,7 @@ func (b *SDKBuilder) pushImage(ctx context.Context, imageName string) error {
 	b.logger.Debug("推送镜像: `%s`", imageName)
 
 	// 创建认证配置
-	authConfig := b.createAuthConfig()
+	authConfig := createAuthConfig(b.loginFor(imageName))
 
 	// 编码认证信息
 	authConfigBytes, err := json.Marshal(authConfig)
@@ -290,7 +322,7 @@ func (b *SDKBuilder) pushImage(ctx context.Context, imageName string) error {
 	defer pushResponse.Close()
 
 	// 读取推送输出
-	if err := b.readPushOutput(pushResponse); err != nil {
+	if err := b.readPushOutput(pushResponse, progressFrom(ctx)); err != nil {
 		return fmt.Errorf("读取推送输出失败: %w", err)
 	}
 
@@ -298,11 +330,13 @@ func (b *SDKBuilder) pushImage(ctx context.Context, imageName string) error {
 	return nil
 }
 
-// readPushOutput 读取推送输出
-func (b *SDKBuilder) readPushOutput(reader io.Reader) error {
+// readPushOutput 读取推送输出，按层状态报告推送进度
+func (b *SDKBuilder) readPushOutput(reader io.Reader, report ProgressFunc) error {
+	progress := newBlobProgress(report)
 	decoder := json.NewDecoder(reader)
 	for {
 		var message struct {
+			ID       string `json:"id"`
 			Status   string `json:"status"`
 			Progress string `json:"progress"`
 			Error    string `json:"error"`
@@ -321,41 +355,47 @@ func (b *SDKBuilder) readPushOutput(reader io.Reader) error {
 
 		if message.Status != "" {
 			b.logger.Debug("推送状态: %s %s", message.Status, message.Progress)
+			progress.pushStatus(message.ID, message.Status)
 		}
 	}
 
 	return nil
 }
 
-// WriteDockerfile 写入 Dockerfile（多架构构建时需要）
-func (b *SDKBuilder) WriteDockerfile(sourceImage string) error {
+// writeBuildContext 在临时目录中写入 Dockerfile（多架构构建时需要），返回构建上下文目录
+func (b *SDKBuilder) writeBuildContext(sourceImage string) (string, error) {
 	b.logger.Debug("写入 Dockerfile，源镜像: `%s`", sourceImage)
 
-	content := fmt.Sprintf("FROM %s\n", sourceImage)
-
-	file, err := os.Create("Dockerfile")
+	dir, err := os.MkdirTemp("", "sync-image-build-")
 	if err != nil {
-		return fmt.Errorf("创建 Dockerfile 失败: %w", err)
+		return "", fmt.Errorf("创建构建上下文目录失败: %w", err)
 	}
-	defer file.Close()
 
-	if _, err := file.WriteString(content); err != nil {
-		return fmt.Errorf("写入 Dockerfile 失败: %w", err)
+	content := fmt.Sprintf("FROM %s\n", sourceImage)
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0644); err != nil {
+		b.removeBuildContext(dir)
+		return "", fmt.Errorf("写入 Dockerfile 失败: %w", err)
 	}
 
-	b.logger.Debug("成功写入 Dockerfile")
-	return nil
+	b.logger.Debug("成功写入 Dockerfile: `%s`", dir)
+	return dir, nil
 }
 
-// cleanupDockerfile 清理 Dockerfile
-func (b *SDKBuilder) cleanupDockerfile() {
-	if err := os.Remove("Dockerfile"); err != nil && !os.IsNotExist(err) {
-		b.logger.Warn("清理 Dockerfile 失败: %v", err)
+// removeBuildContext 清理临时构建上下文目录
+func (b *SDKBuilder) removeBuildContext(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		b.logger.Warn("清理构建上下文失败: %v", err)
 	}
 }
 
-// ensureMultiPlatformBuilder 确保有支持多平台的构建器
+// ensureMultiPlatformBuilder 确保有支持多平台的构建器，每个构建器实例只设置一次
 func (b *SDKBuilder) ensureMultiPlatformBuilder() error {
+	b.builderMu.Lock()
+	defer b.builderMu.Unlock()
+	if b.builderReady {
+		return nil
+	}
+
 	b.logger.Info("设置多平台 buildx 构建器")
 
 	// 创建新的构建器
@@ -397,42 +437,54 @@ func (b *SDKBuilder) ensureMultiPlatformBuilder() error {
 		b.logger.Warn("启动构建器失败，但继续尝试构建: %v\n输出:\n```\n%s\n```", err, bootstrapOutput)
 	}
 
+	b.builderReady = true
 	b.logger.Info("多平台 buildx 构建器设置完成")
 	return nil
 }
 
-// ensureDockerLogin 确保 Docker 登录（统一的登录方法）
-func (b *SDKBuilder) ensureDockerLogin(ctx context.Context) error {
-	if !b.hasCredentials() {
+// ensureDockerLogin 确保已登录到目标镜像所在的仓库（统一的登录方法）
+// 每个仓库只登录一次，并发构建时避免同时写入 Docker CLI 的配置文件
+func (b *SDKBuilder) ensureDockerLogin(ctx context.Context, targetImage string) error {
+	login := b.loginFor(targetImage)
+	if !hasCredentials(login) {
 		b.logger.Debug("跳过 Docker 登录（无凭据配置）")
 		return nil
 	}
 
-	registryAddr := b.getRegistryAddress()
+	b.loginMu.Lock()
+	defer b.loginMu.Unlock()
+	if b.loggedIn[login.Registry] {
+		return nil
+	}
 
 	// 1. 首先进行 SDK 登录
-	if err := b.Login(ctx); err != nil {
+	if err := b.login(ctx, login); err != nil {
 		return fmt.Errorf("Docker SDK 登录失败: %w", err)
 	}
 
 	// 2. 然后进行 CLI 登录（用于多架构构建）
-	return b.ensureCLILogin(registryAddr)
+	if err := b.ensureCLILogin(login); err != nil {
+		return err
+	}
+
+	b.loggedIn[login.Registry] = true
+	return nil
 }
 
 // ensureCLILogin 确保 CLI 环境下的 Docker 登录（用于多架构构建）
-func (b *SDKBuilder) ensureCLILogin(registryAddr string) error {
-	b.logger.Debug("确保 CLI 环境下的 Docker 登录: `%s`", registryAddr)
+func (b *SDKBuilder) ensureCLILogin(login RegistryLogin) error {
+	b.logger.Debug("确保 CLI 环境下的 Docker 登录: `%s`", getRegistryAddress(login))
 
 	var loginCmd *exec.Cmd
-	if b.config.Registry == "" {
+	if login.Registry == "" {
 		// Docker Hub 登录
-		loginCmd = exec.Command("docker", "login", "-u", b.config.Username, "--password-stdin")
+		loginCmd = exec.Command("docker", "login", "-u", login.Username, "--password-stdin")
 	} else {
 		// 私有仓库登录
-		loginCmd = exec.Command("docker", "login", b.config.Registry, "-u", b.config.Username, "--password-stdin")
+		loginCmd = exec.Command("docker", "login", login.Registry, "-u", login.Username, "--password-stdin")
 	}
 
-	loginCmd.Stdin = strings.NewReader(b.config.Password)
+	loginCmd.Stdin = strings.NewReader(login.Password)
 
 	var loginOut bytes.Buffer
 	loginCmd.Stdout = &loginOut
@@ -448,8 +500,8 @@ func (b *SDKBuilder) ensureCLILogin(registryAddr string) error {
 	return nil
 }
 
-// execBuildxCommand 执行 buildx 命令
-func (b *SDKBuilder) execBuildxCommand(targetImage, platforms string) error {
+// execBuildxCommand 在指定的构建上下文目录中执行 buildx 命令，report 不为空时报告推送进度
+func (b *SDKBuilder) execBuildxCommand(contextDir, targetImage, platforms string, report ProgressFunc) error {
 	// 构建参数
 	args := []string{"buildx", "build"}
 
@@ -457,7 +509,7 @@ func (b *SDKBuilder) execBuildxCommand(targetImage, platforms string) error {
 	args = append(args, "--platform", platforms)
 
 	// 设置标签和其他参数
-	args = append(args, "-t", targetImage, "--progress", "plain", ".", "--push")
+	args = append(args, "-t", targetImage, "--progress", "plain", contextDir, "--push")
 
 	b.logger.Debug("执行 Docker buildx 命令: `docker %s`", strings.Join(args, " "))
 
@@ -468,9 +520,9 @@ func (b *SDKBuilder) execBuildxCommand(targetImage, platforms string) error {
 	}
 
 	cmd := exec.Command("docker", cleanArgs...)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+	out := &buildxOutput{progress: newBlobProgress(report)}
+	cmd.Stdout = out
+	cmd.Stderr = out
 
 	err := cmd.Run()
 	output := out.String()
@@ -568,8 +620,8 @@ func (b *SDKBuilder) parseManifestArchitectures(output []byte) ([]string, error)
 	return architectures, nil
 }
 
-// chooseBuildStrategy 选择构建策略
-func (b *SDKBuilder) chooseBuildStrategy(ctx context.Context, sourceImage, targetImage, targetPlatforms string, upstreamArchs []string) error {
+// chooseBuildStrategy 选择构建策略，架构信息写入 result
+func (b *SDKBuilder) chooseBuildStrategy(ctx context.Context, sourceImage, targetImage, targetPlatforms string, upstreamArchs []string, result *BuildResult) error {
 	requestedPlatforms := strings.Split(targetPlatforms, ",")
 
 	// 清理平台字符串
@@ -588,7 +640,7 @@ func (b *SDKBuilder) chooseBuildStrategy(ctx context.Context, sourceImage, targe
 	}
 
 	// 生成架构信息
-	b.generateArchitectureInfo(upstreamArchs, requestedPlatforms, supportedPlatforms)
+	result.ArchitectureInfo = b.generateArchitectureInfo(upstreamArchs, requestedPlatforms, supportedPlatforms)
 
 	// 如果支持的平台少于请求的平台，记录警告
 	if len(supportedPlatforms) < len(requestedPlatforms) {
@@ -609,7 +661,7 @@ func (b *SDKBuilder) chooseBuildStrategy(ctx context.Context, sourceImage, targe
 }
 
 // generateArchitectureInfo 生成架构信息
-func (b *SDKBuilder) generateArchitectureInfo(upstreamArchs, requestedPlatforms, supportedPlatforms []string) {
+func (b *SDKBuilder) generateArchitectureInfo(upstreamArchs, requestedPlatforms, supportedPlatforms []string) string {
 	var info strings.Builder
 
 	info.WriteString("🏗️ **架构信息**:\n")
@@ -631,12 +683,7 @@ func (b *SDKBuilder) generateArchitectureInfo(upstreamArchs, requestedPlatforms,
 		info.WriteString(fmt.Sprintf("⚠️ **跳过架构**: `%s` (上游不支持)\n", strings.Join(unsupported, ", ")))
 	}
 
-	b.lastArchInfo = info.String()
-}
-
-// GetLastArchitectureInfo 获取最后一次构建的架构信息
-func (b *SDKBuilder) GetLastArchitectureInfo() string {
-	return b.lastArchInfo
+	return info.String()
 }
 
 // filterSupportedPlatforms 过滤上游支持的平台
@@ -676,15 +723,35 @@ func (b *SDKBuilder) cleanArchitectures(architectures []string) []string {
 	return cleaned
 }
 
+// defaultLogin 返回默认目标仓库的登录凭据
+func (b *SDKBuilder) defaultLogin() RegistryLogin {
+	return RegistryLogin{
+		Registry: b.config.Registry,
+		Username: b.config.Username,
+		Password: b.config.Password,
+	}
+}
+
+// loginFor 返回目标镜像所在仓库的登录凭据，没有单独配置时使用默认凭据
+func (b *SDKBuilder) loginFor(targetImage string) RegistryLogin {
+	host := strings.SplitN(targetImage, "/", 2)[0]
+	for _, login := range b.config.ExtraLogins {
+		if login.Registry != "" && login.Registry == host {
+			return login
+		}
+	}
+	return b.defaultLogin()
+}
+
 // createAuthConfig 创建统一的认证配置
-func (b *SDKBuilder) createAuthConfig() registry.AuthConfig {
+func createAuthConfig(login RegistryLogin) registry.AuthConfig {
 	authConfig := registry.AuthConfig{
-		Username:      b.config.Username,
-		Password:      b.config.Password,
-		ServerAddress: b.config.Registry,
+		Username:      login.Username,
+		Password:      login.Password,
+		ServerAddress: login.Registry,
 	}
 
-	if b.config.Registry == "" {
+	if login.Registry == "" {
 		authConfig.ServerAddress = "https://index.docker.io/v1/"
 	}
 
@@ -692,16 +759,16 @@ func (b *SDKBuilder) createAuthConfig() registry.AuthConfig {
 }
 
 // hasCredentials 检查是否有登录凭据
-func (b *SDKBuilder) hasCredentials() bool {
-	return b.config.Username != "" && b.config.Password != ""
+func hasCredentials(login RegistryLogin) bool {
+	return login.Username != "" && login.Password != ""
 }
 
 // getRegistryAddress 获取注册表地址
-func (b *SDKBuilder) getRegistryAddress() string {
-	if b.config.Registry == "" {
+func getRegistryAddress(login RegistryLogin) string {
+	if login.Registry == "" {
 		return "Docker Hub"
 	}
-	return b.config.Registry
+	return login.Registry
 }
 
 // getUnsupportedPlatforms 获取不支持的平台
@@ -724,38 +791,159 @@ func (b *SDKBuilder) getUnsupportedPlatforms(requested, upstream []string) []str
 	return unsupported
 }
 
+// Target 镜像同步目标
+type Target struct {
+	Registry  string
+	Namespace string
+	Naming    utils.TargetNaming
+}
+
+// TargetFromConfig 根据仓库配置创建同步目标，配置为空时返回空目标
+func TargetFromConfig(cfg *config.GenericRegistryConfig) Target {
+	target := Target{Naming: cfg.TargetNaming()}
+	if cfg != nil {
+		target.Registry = cfg.Registry
+		target.Namespace = cfg.Namespace
+	}
+	return target
+}
+
+// TransformResult 镜像名称转换结果
+type TransformResult struct {
+	SourceImage   string   // 标准化后的源镜像，目标镜像名称按它计算
+	UpstreamImage string   // 实际拉取的上游镜像，源镜像所在仓库被重定向时与 SourceImage 不同
+	TargetImage   string   // 目标镜像
+	Destination   string   // 规则指定的目标仓库名称，为空表示默认目标
+	Rules         []string // 实际生效的转换规则
+}
+
+// Redirected 返回上游镜像是否被重定向
+func (r *TransformResult) Redirected() bool {
+	return r.UpstreamImage != r.SourceImage
+}
+
 // ImageTransformer 镜像名称转换器
 type ImageTransformer struct {
-	parser *utils.ImageNameParser
-	rules  map[string]string
-	logger logger.Logger
+	parser       *utils.ImageNameParser
+	destinations map[string]Target
+	redirects    map[string]string
+	mapping      *mapping.Index
+	logger       logger.Logger
 }
 
 // NewImageTransformer 创建新的镜像名称转换器
-func NewImageTransformer(rules map[string]string, log logger.Logger) *ImageTransformer {
+// rules 按优先级和书写顺序应用，mode 为 first_match 时只应用第一条匹配的规则
+func NewImageTransformer(rules config.RuleList, mode string, log logger.Logger) *ImageTransformer {
 	return &ImageTransformer{
-		parser: utils.NewImageNameParser(rules),
-		rules:  rules,
+		parser: utils.NewImageNameParser(rules.RewriteRules(), mode == config.RulesModeFirstMatch),
 		logger: log,
 	}
 }
 
+// SetMappingIndex 设置映射索引，设置后转换结果会覆盖其他源仓库的目标仓库时返回错误
+func (t *ImageTransformer) SetMappingIndex(index *mapping.Index) {
+	t.mapping = index
+}
+
+// SetDestinations 设置规则可以引用的目标仓库
+// 未设置时忽略规则上的 destination，所有镜像都同步到默认目标
+func (t *ImageTransformer) SetDestinations(destinations map[string]Target) {
+	t.destinations = destinations
+}
+
+// SetRedirects 设置上游仓库域名重定向表，重定向只影响实际拉取的上游镜像
+func (t *ImageTransformer) SetRedirects(redirects map[string]string) {
+	t.redirects = redirects
+}
+
 // Transform 转换镜像名称
-func (t *ImageTransformer) Transform(originalImage, targetRegistry, targetNamespace string) (sourceImage, targetImage string, err error) {
+// 第一条指定了 destination 的生效规则决定目标仓库，没有时使用 defaultTarget
+func (t *ImageTransformer) Transform(originalImage string, defaultTarget Target) (*TransformResult, error) {
+	return t.TransformWithRepository(originalImage, defaultTarget, "")
+}
+
+// TransformWithRepository 转换镜像名称，repository 不为空时替换规则和命名策略生成的目标仓库名称
+// 目标仓库地址和命名空间仍由规则决定，标签保持不变
+func (t *ImageTransformer) TransformWithRepository(originalImage string, defaultTarget Target, repository string) (*TransformResult, error) {
+	return t.transform(originalImage, defaultTarget, repository, "")
+}
+
+// TransformToDestination 转换镜像名称并同步到指定名称的目标仓库，忽略规则上的 destination
+// 规则的名称改写仍然生效，repository 的含义与 TransformWithRepository 相同
+func (t *ImageTransformer) TransformToDestination(originalImage, destination, repository string) (*TransformResult, error) {
+	target, ok := t.destinations[destination]
+	if !ok {
+		return &TransformResult{SourceImage: t.parser.NormalizeImageName(originalImage)},
+			errors.NewValidationError(fmt.Sprintf("目标仓库 %s 不存在", destination))
+	}
+	return t.transform(originalImage, target, repository, destination)
+}
+
+// transform 执行镜像名称转换，destination 不为空时使用 defaultTarget 作为该目标仓库，不再按规则选择
+func (t *ImageTransformer) transform(originalImage string, defaultTarget Target, repository, destination string) (*TransformResult, error) {
 	t.logger.Debug("开始转换镜像名称: %s", originalImage)
 
 	// 标准化源镜像名称
-	sourceImage = t.parser.NormalizeImageName(originalImage)
+	result := &TransformResult{SourceImage: t.parser.NormalizeImageName(originalImage)}
+
+	// 已停止更新的仓库从新域名拉取，目标镜像名称保持不变
+	result.UpstreamImage = result.SourceImage
+	if upstream, ok := utils.RedirectReference(result.SourceImage, t.redirects); ok {
+		result.UpstreamImage = upstream
+		t.logger.Info("上游仓库已重定向: %s -> %s", result.SourceImage, upstream)
+	}
 
 	// 应用转换规则
-	transformedName := t.parser.TransformImageName(sourceImage, t.rules)
+	transformedName, applied := t.parser.TransformImageNameWithTrace(result.SourceImage)
+	target := defaultTarget
+	result.Destination = destination
+	for _, rule := range applied {
+		result.Rules = append(result.Rules, rule.DisplayName())
+		if rule.Destination == "" || result.Destination != "" || t.destinations == nil {
+			continue
+		}
 
-	// 构建目标镜像名称
-	targetImage = utils.BuildTargetImageName(transformedName, targetRegistry, targetNamespace)
+		destination, ok := t.destinations[rule.Destination]
+		if !ok {
+			return result, errors.NewValidationError(fmt.Sprintf("转换规则 %s 引用的目标仓库 %s 不存在", rule.DisplayName(), rule.Destination))
+		}
+		result.Destination = rule.Destination
+		target = destination
+	}
+	if len(result.Rules) > 0 {
+		t.logger.Debug("应用转换规则: %s", strings.Join(result.Rules, ", "))
+	}
 
-	t.logger.Info("镜像名称转换完成: %s -> %s", sourceImage, targetImage)
+	// 构建目标镜像名称，指定了目标仓库名称时按原样使用
+	if repository != "" {
+		transformedName = repository + tagSuffix(result.SourceImage)
+		target.Naming = utils.TargetNaming{Strategy: utils.NamingFullPath}
+	}
+	result.TargetImage = utils.BuildTargetImageNameWithNaming(result.SourceImage, transformedName, target.Registry, target.Namespace, target.Naming)
 
-	return sourceImage, targetImage, nil
+	// 检查目标仓库是否已被其他源仓库占用
+	if t.mapping != nil {
+		if err := t.mapping.Check(result.SourceImage, result.TargetImage); err != nil {
+			return result, err
+		}
+	}
+
+	if result.Destination != "" {
+		t.logger.Info("镜像名称转换完成: %s -> %s（目标仓库: %s）", result.SourceImage, result.TargetImage, result.Destination)
+	} else {
+		t.logger.Info("镜像名称转换完成: %s -> %s", result.SourceImage, result.TargetImage)
+	}
+
+	return result, nil
+}
+
+// tagSuffix 返回镜像的 ":tag" 和 "@digest" 后缀
+func tagSuffix(image string) string {
+	ref, err := utils.ParseReference(image)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(ref.String(), ref.Name())
 }
 
 // ValidateTransformation 验证转换结果
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+)
+
+// ProgressFunc 推送进度回调，done 和 total 为已推送完成和已发现的 blob 数量
+type ProgressFunc func(done, total int)
+
+// progressKey 推送进度回调在 context 中的键
+type progressKey struct{}
+
+// WithProgress 返回带推送进度回调的 context，BuildAndPush 推送 blob 时调用
+// 构建器由多个 worker 共享，进度回调随每次调用传入
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFrom 返回 context 中的推送进度回调，未设置时返回 nil
+func progressFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// blobProgress 统计推送的 blob 数量，数量变化时调用回调
+type blobProgress struct {
+	report ProgressFunc
+	blobs  map[string]bool // blob 标识 -> 是否已完成
+	done   int
+}
+
+// newBlobProgress 创建 blob 推送进度统计，report 可以为空
+func newBlobProgress(report ProgressFunc) *blobProgress {
+	return &blobProgress{report: report, blobs: make(map[string]bool)}
+}
+
+// seen 记录发现的 blob
+func (p *blobProgress) seen(id string) {
+	if _, ok := p.blobs[id]; ok {
+		return
+	}
+	p.blobs[id] = false
+	p.notify()
+}
+
+// finished 记录推送完成的 blob
+func (p *blobProgress) finished(id string) {
+	if p.blobs[id] {
+		return
+	}
+	p.blobs[id] = true
+	p.done++
+	p.notify()
+}
+
+// notify 调用进度回调
+func (p *blobProgress) notify() {
+	if p.report != nil {
+		p.report(p.done, len(p.blobs))
+	}
+}
+
+// pushStatus 处理 docker push 输出中单个层的状态
+func (p *blobProgress) pushStatus(id, status string) {
+	if id == "" {
+		return
+	}
+	switch {
+	case status == "Pushed", status == "Layer already exists", strings.HasPrefix(status, "Mounted from"):
+		p.finished(id)
+	case status == "Preparing", status == "Waiting", status == "Pushing":
+		p.seen(id)
+	}
+}
+
+// buildxLayerRegexp 匹配 buildx plain 输出中的推送层，如 "#9 pushing layer sha256:ab12... 1.2s done"
+var buildxLayerRegexp = regexp.MustCompile(`pushing layer (sha256:[0-9a-f]+)(.*)$`)
+
+// buildxOutput 收集 buildx 命令的输出，并按行解析推送进度
+// exec.Cmd 的 Stdout 和 Stderr 为同一个 writer 时不会并发调用 Write
+type buildxOutput struct {
+	bytes.Buffer
+	progress *blobProgress
+	pending  []byte
+}
+
+// Write 实现 io.Writer
+func (o *buildxOutput) Write(data []byte) (int, error) {
+	o.pending = append(o.pending, data...)
+	for {
+		i := bytes.IndexByte(o.pending, '\n')
+		if i < 0 {
+			break
+		}
+		o.parseLine(string(o.pending[:i]))
+		o.pending = o.pending[i+1:]
+	}
+	return o.Buffer.Write(data)
+}
+
+// parseLine 解析单行输出中的推送进度
+func (o *buildxOutput) parseLine(line string) {
+	match := buildxLayerRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return
+	}
+	o.progress.seen(match[1])
+	if strings.HasSuffix(strings.TrimSpace(match[2]), "done") {
+		o.progress.finished(match[1])
+	}
+}
@@ -0,0 +1,219 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+	"golang.org/x/oauth2"
+
+	"sync-image/internal/config"
+	"sync-image/pkg/errors"
+	"sync-image/pkg/logger"
+)
+
+const (
+	// appJWTLifetime GitHub App JWT 的有效期，GitHub 允许的上限为 10 分钟
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew 签发时间提前的时长，避免与 GitHub 的时钟偏差导致 JWT 尚未生效
+	appJWTClockSkew = time.Minute
+	// installationTokenRefresh 安装令牌在过期前多久刷新
+	installationTokenRefresh = 5 * time.Minute
+)
+
+// AppAuth GitHub App 认证：用私钥签名 JWT，换取各个安装的访问令牌
+// 同一个 App 可以安装到多个账号，按仓库查找安装并缓存令牌，过期前自动刷新
+type AppAuth struct {
+	appID          int64
+	installationID int64  // 配置的默认安装，为 0 时按仓库查找
+	owner          string // 默认安装所在的账号，其他账号的仓库按仓库查找安装
+	key            *rsa.PrivateKey
+	client         *github.Client // 使用 JWT 认证，只用于 App 接口
+	logger         logger.Logger
+
+	mu            sync.Mutex
+	installations map[string]int64 // owner/repo -> 安装 ID
+	tokens        map[int64]*oauth2.Token
+}
+
+// NewAppAuth 根据配置创建 GitHub App 认证，私钥可以直接配置或从文件读取
+func NewAppAuth(cfg *config.GitHubConfig, log logger.Logger) (*AppAuth, error) {
+	key, err := loadAppPrivateKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &AppAuth{
+		appID:          cfg.AppID,
+		installationID: cfg.InstallationID,
+		owner:          cfg.User,
+		key:            key,
+		logger:         log,
+		installations:  make(map[string]int64),
+		tokens:         make(map[int64]*oauth2.Token),
+	}
+	auth.client = newGitHubClient(&http.Client{
+		Transport: &appTransport{auth: auth, base: NewTransport(nil, log)},
+	}, cfg, log)
+	return auth, nil
+}
+
+// JWT 签发用于调用 App 接口的 JWT
+func (a *AppAuth) JWT() (string, error) {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": a.appID,
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.NewSystemError("签名 GitHub App JWT 失败", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationID 返回 App 在仓库上的安装 ID
+// 配置了默认安装时，默认账号下的仓库直接使用该安装，其他仓库通过接口查找并缓存
+func (a *AppAuth) InstallationID(ctx context.Context, owner, repo string) (int64, error) {
+	if a.installationID != 0 && strings.EqualFold(owner, a.owner) {
+		return a.installationID, nil
+	}
+
+	key := owner + "/" + repo
+	a.mu.Lock()
+	id, ok := a.installations[key]
+	a.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	installation, _, err := a.client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	if err != nil {
+		return 0, errors.NewGitHubError(fmt.Sprintf("查找 GitHub App 在仓库 %s 上的安装失败", key), err)
+	}
+	id = installation.GetID()
+	a.logger.Info("GitHub App 在仓库 %s 上的安装 ID: %d", key, id)
+
+	a.mu.Lock()
+	a.installations[key] = id
+	a.mu.Unlock()
+	return id, nil
+}
+
+// Token 返回安装的访问令牌，缓存的令牌临近过期时重新获取
+func (a *AppAuth) Token(ctx context.Context, installationID int64) (*oauth2.Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if token, ok := a.tokens[installationID]; ok && time.Until(token.Expiry) > installationTokenRefresh {
+		return token, nil
+	}
+
+	installationToken, _, err := a.client.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return nil, errors.NewGitHubError(fmt.Sprintf("获取 GitHub App 安装 %d 的访问令牌失败", installationID), err).
+			WithContext("installation_id", installationID)
+	}
+
+	token := &oauth2.Token{
+		AccessToken: installationToken.GetToken(),
+		TokenType:   "token",
+		Expiry:      installationToken.GetExpiresAt(),
+	}
+	a.tokens[installationID] = token
+	a.logger.Debug("已获取 GitHub App 安装 %d 的访问令牌，有效期至 %s", installationID, token.Expiry.Format(time.RFC3339))
+	return token, nil
+}
+
+// TokenSource 返回仓库所在安装的令牌来源，第一次使用时才查找安装
+func (a *AppAuth) TokenSource(owner, repo string) oauth2.TokenSource {
+	return &installationTokenSource{auth: a, owner: owner, repo: repo}
+}
+
+// installationTokenSource 按仓库获取安装令牌的 oauth2.TokenSource
+type installationTokenSource struct {
+	auth  *AppAuth
+	owner string
+	repo  string
+}
+
+// Token 实现 oauth2.TokenSource
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.Background()
+	id, err := s.auth.InstallationID(ctx, s.owner, s.repo)
+	if err != nil {
+		return nil, err
+	}
+	return s.auth.Token(ctx, id)
+}
+
+// appTransport 为 App 接口的请求添加 JWT 认证
+type appTransport struct {
+	auth *AppAuth
+	base http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := t.auth.JWT()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(clone)
+}
+
+// loadAppPrivateKey 读取并解析 GitHub App 私钥，支持 PKCS#1 和 PKCS#8 格式的 PEM
+// 通过环境变量传入时换行可能被写成 \n，解析前还原
+func loadAppPrivateKey(cfg *config.GitHubConfig) (*rsa.PrivateKey, error) {
+	data := cfg.AppPrivateKey
+	if data == "" && cfg.AppPrivateKeyFile != "" {
+		content, err := os.ReadFile(cfg.AppPrivateKeyFile)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("读取 GitHub App 私钥文件失败: %v", err))
+		}
+		data = string(content)
+	}
+	if data == "" {
+		return nil, errors.NewConfigError("未配置 GitHub App 私钥")
+	}
+	if !strings.Contains(data, "\n") {
+		data = strings.ReplaceAll(data, `\n`, "\n")
+	}
+
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.NewConfigError("GitHub App 私钥不是有效的 PEM 格式")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.NewConfigError(fmt.Sprintf("解析 GitHub App 私钥失败: %v", err))
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.NewConfigError("GitHub App 私钥必须是 RSA 私钥")
+	}
+	return key, nil
+}
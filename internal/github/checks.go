@@ -0,0 +1,69 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v47/github"
+
+	"sync-image/pkg/errors"
+)
+
+// maxCheckRunOutput Check Run 输出中 summary 和 text 的最大长度
+const maxCheckRunOutput = 65535
+
+// CheckRun 在提交上发布的已完成的 Check Run
+type CheckRun struct {
+	Name       string
+	HeadSHA    string
+	Conclusion string // success、failure 或 neutral
+	Title      string
+	Summary    string // Markdown
+	Text       string // Markdown，可以为空
+}
+
+// CreateCheckRun 在提交上创建已完成的 Check Run，配置了 Run ID 时链接到 Actions 运行记录
+// 需要 Token 有 checks 写权限；summary 和 text 超出 GitHub 的长度限制时截断
+func (c *DefaultClient) CreateCheckRun(ctx context.Context, run *CheckRun) error {
+	opts := github.CreateCheckRunOptions{
+		Name:        run.Name,
+		HeadSHA:     run.HeadSHA,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(run.Conclusion),
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output: &github.CheckRunOutput{
+			Title:   github.String(run.Title),
+			Summary: github.String(truncateOutput(run.Summary)),
+		},
+	}
+	if run.Text != "" {
+		opts.Output.Text = github.String(truncateOutput(run.Text))
+	}
+	if c.config.RunID != "" {
+		opts.DetailsURL = github.String(fmt.Sprintf("https://github.com/%s/%s/actions/runs/%s", c.config.User, c.config.Repo, c.config.RunID))
+	}
+
+	checkRun, _, err := c.client.Checks.CreateCheckRun(ctx, c.config.User, c.config.Repo, opts)
+	if err != nil {
+		return errors.NewGitHubError(fmt.Sprintf("在提交 %s 上创建 Check Run %s 失败", run.HeadSHA, run.Name), err).
+			WithContext("head_sha", run.HeadSHA)
+	}
+	c.logger.Info("已在提交 %s 上创建 Check Run %s: %s", run.HeadSHA, run.Name, checkRun.GetHTMLURL())
+	return nil
+}
+
+// truncateOutput 截断超出长度限制的 Check Run 输出，不截断多字节字符
+func truncateOutput(s string) string {
+	const notice = "\n\n…（内容过长，已截断）"
+	if len(s) <= maxCheckRunOutput {
+		return s
+	}
+
+	s = s[:maxCheckRunOutput-len(notice)]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + notice
+}
@@ -3,22 +3,27 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/google/go-github/v47/github"
 	"golang.org/x/oauth2"
 
 	"sync-image/internal/config"
+	"sync-image/internal/source"
 	"sync-image/pkg/errors"
 	"sync-image/pkg/logger"
 	"sync-image/pkg/utils"
 )
 
 // Client GitHub 客户端接口
+// 除作为搬运请求来源外，还提供只有 GitHub 支持的组织成员检查和 Check Run
 type Client interface {
-	GetPendingIssues(ctx context.Context) ([]*github.Issue, error)
-	AddComment(ctx context.Context, issue *github.Issue, comment string) error
-	AddLabels(ctx context.Context, issue *github.Issue, labels []string) error
-	CloseIssue(ctx context.Context, issue *github.Issue) error
+	source.RequestSource
+	IsMember(ctx context.Context, org, team, user string) (bool, error)
+	CreateCheckRun(ctx context.Context, run *CheckRun) error
 }
 
 // DefaultClient 默认 GitHub 客户端实现
@@ -29,113 +34,291 @@ type DefaultClient struct {
 }
 
 // NewClient 创建新的 GitHub 客户端
-func NewClient(cfg *config.GitHubConfig, log logger.Logger) Client {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: cfg.Token},
-	)
-	tc := oauth2.NewClient(context.Background(), ts)
-	
+// 配置了 GitHub App 时使用 App 的安装令牌，评论以 App 的机器人身份发布；否则使用个人访问令牌
+func NewClient(cfg *config.GitHubConfig, log logger.Logger) (Client, error) {
+	var ts oauth2.TokenSource
+	if cfg.AppID != 0 {
+		auth, err := NewAppAuth(cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		ts = auth.TokenSource(cfg.User, cfg.Repo)
+		log.Info("使用 GitHub App %d 认证", cfg.AppID)
+	} else {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: cfg.Token},
+		)
+	}
+	tc := &http.Client{
+		Transport: &oauth2.Transport{Source: ts, Base: NewTransport(nil, log)},
+	}
+
 	return &DefaultClient{
-		client: github.NewClient(tc),
+		client: newGitHubClient(tc, cfg, log),
 		config: cfg,
 		logger: log,
+	}, nil
+}
+
+// newGitHubClient 创建 go-github 客户端，配置了 API 地址时使用该地址
+func newGitHubClient(httpClient *http.Client, cfg *config.GitHubConfig, log logger.Logger) *github.Client {
+	client := github.NewClient(httpClient)
+	if cfg.APIURL != "" {
+		// GitHub Enterprise Server 或测试用的 API 地址
+		if baseURL, err := url.Parse(strings.TrimSuffix(cfg.APIURL, "/") + "/"); err == nil {
+			client.BaseURL = baseURL
+		} else {
+			log.Warn("无效的 GitHub API 地址 %s，使用默认地址: %v", cfg.APIURL, err)
+		}
+	}
+	return client
+}
+
+// Type 实现 source.RequestSource
+func (c *DefaultClient) Type() string {
+	return config.SourceGitHub
+}
+
+// Identity 返回令牌所属用户的用户名
+// GitHub Actions 的 GITHUB_TOKEN 和 GitHub App 的安装令牌无权读取当前用户，此时返回错误
+func (c *DefaultClient) Identity(ctx context.Context) (string, error) {
+	user, _, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		return "", errors.NewGitHubError("获取当前用户失败", err)
 	}
+	return user.GetLogin(), nil
 }
 
-// GetPendingIssues 获取待处理的 Issues
-func (c *DefaultClient) GetPendingIssues(ctx context.Context) ([]*github.Issue, error) {
+// PendingRequests 获取所有待处理的 Issues，按创建时间从早到晚排序
+func (c *DefaultClient) PendingRequests(ctx context.Context) ([]*source.Request, error) {
 	c.logger.Debug("获取待处理的 Issues")
-	
-	issues, _, err := c.client.Issues.ListByRepo(ctx, c.config.User, c.config.Repo, &github.IssueListByRepoOptions{
+
+	opts := &github.IssueListByRepoOptions{
 		State:     "open",
-		Labels:    []string{"porter"},
+		Labels:    []string{source.LabelRequest},
 		Sort:      "created",
-		Direction: "desc",
+		Direction: "asc", // 先处理较早的请求，避免后来的请求一直被优先处理
 		ListOptions: github.ListOptions{
-			Page:    1,
-			PerPage: 1, // 每次只处理一个 Issue
+			PerPage: 100,
 		},
-	})
-	
+	}
+
+	requests, err := c.listIssues(ctx, opts)
 	if err != nil {
 		return nil, errors.NewGitHubError("获取 Issues 失败", err)
 	}
-	
-	c.logger.Info("找到 %d 个待处理的 Issues", len(issues))
-	return issues, nil
+
+	c.logger.Info("找到 %d 个待处理的 Issues", len(requests))
+	return requests, nil
 }
 
-// AddComment 为 Issue 添加评论
-func (c *DefaultClient) AddComment(ctx context.Context, issue *github.Issue, comment string) error {
-	owner, repo := utils.ExtractRepoInfo(*issue.RepositoryURL)
-	
-	c.logger.Debug("为 Issue #%d 添加评论", issue.GetNumber())
-	
-	_, _, err := c.client.Issues.CreateComment(ctx, owner, repo, issue.GetNumber(), &github.IssueComment{
+// GetRequest 获取指定编号的 Issue
+func (c *DefaultClient) GetRequest(ctx context.Context, number int) (*source.Request, error) {
+	issue, _, err := c.client.Issues.Get(ctx, c.config.User, c.config.Repo, number)
+	if err != nil {
+		return nil, errors.NewGitHubError(
+			fmt.Sprintf("获取 Issue #%d 失败", number),
+			err,
+		).WithContext("issue_number", number)
+	}
+	return ToRequest(issue), nil
+}
+
+// ListComments 获取 Issue 的所有评论，按发布时间从早到晚排序
+func (c *DefaultClient) ListComments(ctx context.Context, number int) ([]*source.Comment, error) {
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var comments []*source.Comment
+	for {
+		page, resp, err := c.client.Issues.ListComments(ctx, c.config.User, c.config.Repo, number, opts)
+		if err != nil {
+			return nil, errors.NewGitHubError(
+				fmt.Sprintf("获取 Issue #%d 的评论失败", number),
+				err,
+			).WithContext("issue_number", number)
+		}
+
+		for _, comment := range page {
+			comments = append(comments, toComment(comment))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return comments, nil
+}
+
+// CreateComment 为 Issue 添加评论，返回评论 ID 用于后续修改
+func (c *DefaultClient) CreateComment(ctx context.Context, request *source.Request, comment string) (int64, error) {
+	c.logger.Debug("为 Issue #%d 添加评论", request.Number)
+
+	created, _, err := c.client.Issues.CreateComment(ctx, c.config.User, c.config.Repo, request.Number, &github.IssueComment{
+		Body: &comment,
+	})
+	if err != nil {
+		return 0, errors.NewGitHubError(
+			fmt.Sprintf("为 Issue #%d 添加评论失败", request.Number),
+			err,
+		).WithContext("issue_number", request.Number)
+	}
+
+	c.logger.Info("成功为 Issue #%d 添加评论", request.Number)
+	return created.GetID(), nil
+}
+
+// EditComment 修改 Issue 评论的内容
+func (c *DefaultClient) EditComment(ctx context.Context, request *source.Request, commentID int64, comment string) error {
+	c.logger.Debug("修改 Issue #%d 的评论 %d", request.Number, commentID)
+
+	_, _, err := c.client.Issues.EditComment(ctx, c.config.User, c.config.Repo, commentID, &github.IssueComment{
 		Body: &comment,
 	})
-	
 	if err != nil {
 		return errors.NewGitHubError(
-			fmt.Sprintf("为 Issue #%d 添加评论失败", issue.GetNumber()),
+			fmt.Sprintf("修改 Issue #%d 的评论失败", request.Number),
 			err,
-		).WithContext("issue_number", issue.GetNumber())
+		).WithContext("issue_number", request.Number).WithContext("comment_id", commentID)
 	}
-	
-	c.logger.Info("成功为 Issue #%d 添加评论", issue.GetNumber())
+
 	return nil
 }
 
 // AddLabels 为 Issue 添加标签
-func (c *DefaultClient) AddLabels(ctx context.Context, issue *github.Issue, labels []string) error {
-	owner, repo := utils.ExtractRepoInfo(*issue.RepositoryURL)
-	
-	c.logger.Debug("为 Issue #%d 添加标签: %v", issue.GetNumber(), labels)
-	
-	_, _, err := c.client.Issues.AddLabelsToIssue(ctx, owner, repo, issue.GetNumber(), labels)
-	
+func (c *DefaultClient) AddLabels(ctx context.Context, request *source.Request, labels []string) error {
+	c.logger.Debug("为 Issue #%d 添加标签: %v", request.Number, labels)
+
+	_, _, err := c.client.Issues.AddLabelsToIssue(ctx, c.config.User, c.config.Repo, request.Number, labels)
 	if err != nil {
 		return errors.NewGitHubError(
-			fmt.Sprintf("为 Issue #%d 添加标签失败", issue.GetNumber()),
+			fmt.Sprintf("为 Issue #%d 添加标签失败", request.Number),
 			err,
-		).WithContext("issue_number", issue.GetNumber()).WithContext("labels", labels)
+		).WithContext("issue_number", request.Number).WithContext("labels", labels)
 	}
-	
-	c.logger.Info("成功为 Issue #%d 添加标签: %v", issue.GetNumber(), labels)
+
+	c.logger.Info("成功为 Issue #%d 添加标签: %v", request.Number, labels)
 	return nil
 }
 
-// CloseIssue 关闭 Issue
-func (c *DefaultClient) CloseIssue(ctx context.Context, issue *github.Issue) error {
-	owner, repo := utils.ExtractRepoInfo(*issue.RepositoryURL)
-	
-	c.logger.Debug("关闭 Issue #%d", issue.GetNumber())
-	
+// CloseRequest 关闭 Issue
+func (c *DefaultClient) CloseRequest(ctx context.Context, request *source.Request) error {
+	c.logger.Debug("关闭 Issue #%d", request.Number)
+
 	state := "closed"
-	_, _, err := c.client.Issues.Edit(ctx, owner, repo, issue.GetNumber(), &github.IssueRequest{
+	_, _, err := c.client.Issues.Edit(ctx, c.config.User, c.config.Repo, request.Number, &github.IssueRequest{
 		State: &state,
 	})
-	
 	if err != nil {
 		return errors.NewGitHubError(
-			fmt.Sprintf("关闭 Issue #%d 失败", issue.GetNumber()),
+			fmt.Sprintf("关闭 Issue #%d 失败", request.Number),
 			err,
-		).WithContext("issue_number", issue.GetNumber())
+		).WithContext("issue_number", request.Number)
 	}
-	
-	c.logger.Info("成功关闭 Issue #%d", issue.GetNumber())
+
+	c.logger.Info("成功关闭 Issue #%d", request.Number)
 	return nil
 }
 
-// IssueProcessor Issue 处理器
+// RemoveLabel 移除 Issue 的标签
+func (c *DefaultClient) RemoveLabel(ctx context.Context, request *source.Request, label string) error {
+	c.logger.Debug("移除 Issue #%d 的标签: %s", request.Number, label)
+
+	_, err := c.client.Issues.RemoveLabelForIssue(ctx, c.config.User, c.config.Repo, request.Number, label)
+	if err != nil {
+		return errors.NewGitHubError(
+			fmt.Sprintf("移除 Issue #%d 的标签失败", request.Number),
+			err,
+		).WithContext("issue_number", request.Number).WithContext("label", label)
+	}
+
+	return nil
+}
+
+// AddReaction 为 Issue 评论添加表情回应，content 为 +1、-1、eyes 等
+func (c *DefaultClient) AddReaction(ctx context.Context, request *source.Request, commentID int64, content string) error {
+	_, _, err := c.client.Reactions.CreateIssueCommentReaction(ctx, c.config.User, c.config.Repo, commentID, content)
+	if err != nil {
+		return errors.NewGitHubError(
+			fmt.Sprintf("为 Issue #%d 的评论添加回应失败", request.Number),
+			err,
+		).WithContext("comment_id", commentID)
+	}
+
+	return nil
+}
+
+// listIssues 分页列出 Issues，跳过 Issues 接口同时返回的 Pull Request
+func (c *DefaultClient) listIssues(ctx context.Context, opts *github.IssueListByRepoOptions) ([]*source.Request, error) {
+	var requests []*source.Request
+	for {
+		page, resp, err := c.client.Issues.ListByRepo(ctx, c.config.User, c.config.Repo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range page {
+			if !issue.IsPullRequest() {
+				requests = append(requests, ToRequest(issue))
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return requests, nil
+}
+
+// ToRequest 将 GitHub Issue 转换为搬运请求
+func ToRequest(issue *github.Issue) *source.Request {
+	request := &source.Request{
+		Number:      issue.GetNumber(),
+		Title:       issue.GetTitle(),
+		Body:        issue.GetBody(),
+		Author:      issue.GetUser().GetLogin(),
+		Maintainer:  maintainerAssociations[issue.GetAuthorAssociation()],
+		State:       issue.GetState(),
+		CreatedAt:   issue.GetCreatedAt(),
+		ClosedAt:    issue.GetClosedAt(),
+		URL:         issue.GetHTMLURL(),
+		PullRequest: issue.IsPullRequest(),
+	}
+	for _, label := range issue.Labels {
+		request.Labels = append(request.Labels, label.GetName())
+	}
+	return request
+}
+
+// toComment 将 GitHub Issue 评论转换为与来源无关的评论
+func toComment(comment *github.IssueComment) *source.Comment {
+	return &source.Comment{
+		ID:         comment.GetID(),
+		Body:       comment.GetBody(),
+		Author:     comment.GetUser().GetLogin(),
+		Maintainer: maintainerAssociations[comment.GetAuthorAssociation()],
+		Bot:        comment.GetUser().GetType() == "Bot",
+		CreatedAt:  comment.GetCreatedAt(),
+	}
+}
+
+// IssueProcessor Issue 处理器，通过 RequestSource 处理任意来源的搬运请求
 type IssueProcessor struct {
-	client Client
+	client source.RequestSource
 	config *config.GitHubConfig
 	logger logger.Logger
+
+	allowTarget bool // 是否所有请求者都可以指定目标仓库名称
+
+	identityOnce sync.Once
+	identity     string // 当前认证用户，无法获取时为空
 }
 
 // NewIssueProcessor 创建新的 Issue 处理器
-func NewIssueProcessor(client Client, cfg *config.GitHubConfig, log logger.Logger) *IssueProcessor {
+func NewIssueProcessor(client source.RequestSource, cfg *config.GitHubConfig, log logger.Logger) *IssueProcessor {
 	return &IssueProcessor{
 		client: client,
 		config: cfg,
@@ -143,25 +326,65 @@ func NewIssueProcessor(client Client, cfg *config.GitHubConfig, log logger.Logge
 	}
 }
 
-// ProcessIssue 处理单个 Issue
-func (p *IssueProcessor) ProcessIssue(ctx context.Context, issue *github.Issue) (imageName, platform string, err error) {
-	p.logger.Info("开始处理 Issue #%d: %s", issue.GetNumber(), issue.GetTitle())
-	
-	// 添加构建进展评论
-	buildURL := fmt.Sprintf("https://github.com/%s/%s/actions/runs/%s", 
-		p.config.User, p.config.Repo, p.config.RunID)
-	progressComment := fmt.Sprintf("[构建进展](%s)", buildURL)
-	
-	if err := p.client.AddComment(ctx, issue, progressComment); err != nil {
-		p.logger.Warn("添加构建进展评论失败: %v", err)
+// SetTargetOverride 设置是否所有请求者都可以在镜像列表中指定目标仓库名称（target）
+// 启用映射索引后占用其他源仓库的目标仓库会被拒绝，可以放开；否则只有维护者可以指定
+func (p *IssueProcessor) SetTargetOverride(allowed bool) {
+	p.allowTarget = allowed
+}
+
+// ProcessIssue 处理单个 Issue，处理进度写入状态评论
+// 请求者超出限制时评论说明、关闭 Issue 并返回 LimitError；检查限制失败时评论说明、保持 Issue 打开并返回 LimitCheckError
+func (p *IssueProcessor) ProcessIssue(ctx context.Context, request *source.Request, status *StatusComment) (requests []utils.ImageRequest, structured bool, err error) {
+	p.logger.Info("开始处理 Issue #%d: %s", request.Number, request.Title)
+	status.Stage(ctx, "开始处理")
+
+	if p.config.Limits.Enabled() {
+		limit, err := p.CheckRequester(ctx, request)
+		if err != nil {
+			// 无法确认请求者是否超出限制时不处理，避免限制因 API 故障失效
+			p.DeferIssue(ctx, request, status, err)
+			return nil, false, &LimitCheckError{Err: err}
+		}
+		if limit != nil {
+			if err := p.RejectIssue(ctx, request, status, limit); err != nil {
+				p.logger.Error("关闭超出限制的 Issue 失败: %v", err)
+			}
+			return nil, false, limit
+		}
+	}
+
+	requests, structured, err = p.ParseIssue(request)
+	if err == nil {
+		status.Stage(ctx, "解析得到 %d 个镜像", len(requests))
+	}
+	return requests, structured, err
+}
+
+// ParseIssue 解析并校验 Issue 中请求的镜像，不会修改 Issue
+// 依次尝试 Issue 表单、内容中的镜像列表代码块（structured 为 true）和标题
+func (p *IssueProcessor) ParseIssue(request *source.Request) (requests []utils.ImageRequest, structured bool, err error) {
+	var imageName, platform string
+	if form, ok := utils.ParseIssueFormRequest(request.Body); ok {
+		// 通过 Issue 表单创建
+		imageName, platform = form.Image, form.Platform
+	} else {
+		// 解析 Issue 内容中的镜像列表
+		requests, structured, err = utils.ParseIssueBody(request.Body, p.allowTarget || request.Maintainer)
+		if err != nil {
+			return nil, true, errors.WrapError(errors.ValidationError, "镜像列表校验失败", err)
+		}
+		if structured {
+			p.logger.Info("解析得到 %d 个镜像", len(requests))
+			return requests, true, nil
+		}
+
+		// 解析 Issue 标题
+		imageName, platform = utils.ParseIssueTitle(request.Title)
 	}
-	
-	// 解析 Issue 标题
-	imageName, platform = utils.ParseIssueTitle(issue.GetTitle())
 	
 	// 验证镜像名称
 	if !utils.IsValidImageName(imageName) {
-		return "", "", errors.NewValidationError(
+		return nil, false, errors.NewValidationError(
 			fmt.Sprintf("无效的镜像名称: %s", imageName),
 		)
 	}
@@ -172,13 +395,29 @@ func (p *IssueProcessor) ProcessIssue(ctx context.Context, issue *github.Issue)
 	
 	p.logger.Info("解析得到镜像名称: %s, 平台: %s", imageName, platform)
 	
-	return imageName, platform, nil
+	return []utils.ImageRequest{{Image: imageName, Platform: platform}}, false, nil
+}
+
+// ResetIssue 移除上次处理添加的结果标签，用于重新同步已关闭的 Issue
+func (p *IssueProcessor) ResetIssue(ctx context.Context, request *source.Request) {
+	for _, label := range request.Labels {
+		switch label {
+		case "success", "failed", "platform", LabelRateLimited:
+			if err := p.client.RemoveLabel(ctx, request, label); err != nil {
+				p.logger.Warn("移除标签失败: %v", err)
+			}
+		}
+	}
 }
 
-// FinishIssue 完成 Issue 处理
-func (p *IssueProcessor) FinishIssue(ctx context.Context, issue *github.Issue, success bool, result string, platform string) error {
+// FinishIssue 完成 Issue 处理，状态评论替换为结果
+func (p *IssueProcessor) FinishIssue(ctx context.Context, request *source.Request, status *StatusComment, success bool, result string, platform string) error {
 	// 添加结果评论
-	if err := p.client.AddComment(ctx, issue, result); err != nil {
+	if status == nil {
+		status = p.NewStatusComment(request)
+	}
+	// 结果评论带有运行标记，每次处理（包括重试）都计入请求者的每日请求数
+	if err := status.Finish(ctx, result+"\n"+runMarker); err != nil {
 		p.logger.Error("添加结果评论失败: %v", err)
 	}
 	
@@ -194,12 +433,12 @@ func (p *IssueProcessor) FinishIssue(ctx context.Context, issue *github.Issue, s
 		labels = append(labels, "platform")
 	}
 	
-	if err := p.client.AddLabels(ctx, issue, labels); err != nil {
+	if err := p.client.AddLabels(ctx, request, labels); err != nil {
 		p.logger.Error("添加标签失败: %v", err)
 	}
 	
 	// 关闭 Issue
-	if err := p.client.CloseIssue(ctx, issue); err != nil {
+	if err := p.client.CloseRequest(ctx, request); err != nil {
 		p.logger.Error("关闭 Issue 失败: %v", err)
 		return err
 	}
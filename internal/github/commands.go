@@ -0,0 +1,140 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v47/github"
+
+	"sync-image/internal/source"
+	"sync-image/pkg/errors"
+	"sync-image/pkg/utils"
+)
+
+// Issue 评论中支持的命令
+const (
+	CommandRetry    = "retry"    // 重新同步失败的镜像
+	CommandPlatform = "platform" // 使用指定架构重新同步
+	CommandTags     = "tags"     // 为已同步的镜像添加额外标签
+	CommandCancel   = "cancel"   // 取消尚未处理的请求
+)
+
+// 评论回应的表情
+const (
+	ReactionAccepted = "+1"
+	ReactionRejected = "-1"
+)
+
+// SlashCommand Issue 评论中的斜杠命令
+type SlashCommand struct {
+	Name string
+	Args []string // 逗号或空格分隔的参数
+}
+
+// String 返回命令的原始形式
+func (c *SlashCommand) String() string {
+	if len(c.Args) == 0 {
+		return "/" + c.Name
+	}
+	return "/" + c.Name + " " + strings.Join(c.Args, ",")
+}
+
+// ParseSlashCommand 解析评论第一行中的斜杠命令，不是命令时 ok 为 false
+func ParseSlashCommand(body string) (command *SlashCommand, ok bool, err error) {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(body), "\n", 2)[0])
+	if !strings.HasPrefix(line, "/") {
+		return nil, false, nil
+	}
+
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		return nil, false, nil
+	}
+	command = &SlashCommand{Name: strings.ToLower(fields[0])}
+	for _, field := range fields[1:] {
+		for _, arg := range strings.Split(field, ",") {
+			if arg = strings.TrimSpace(arg); arg != "" {
+				command.Args = append(command.Args, arg)
+			}
+		}
+	}
+
+	switch command.Name {
+	case CommandRetry, CommandCancel:
+		if len(command.Args) > 0 {
+			return command, true, fmt.Errorf("/%s 不需要参数", command.Name)
+		}
+	case CommandPlatform:
+		if len(command.Args) == 0 {
+			return command, true, fmt.Errorf("用法: /platform linux/arm64[,linux/amd64]")
+		}
+		for _, platform := range command.Args {
+			if !utils.IsValidPlatform(platform) {
+				return command, true, fmt.Errorf("无效的平台: %s", platform)
+			}
+		}
+	case CommandTags:
+		if len(command.Args) == 0 {
+			return command, true, fmt.Errorf("用法: /tags a,b")
+		}
+		for _, tag := range command.Args {
+			if !utils.IsValidTag(tag) {
+				return command, true, fmt.Errorf("无效的标签: %s", tag)
+			}
+		}
+	default:
+		return command, true, fmt.Errorf("不支持的命令 /%s，可用命令: /retry、/platform、/tags、/cancel", command.Name)
+	}
+
+	return command, true, nil
+}
+
+// LoadCommentEvent 读取 GitHub Actions 触发事件文件中的 Issue 评论事件
+// Gitea Actions 的事件文件与 GitHub 兼容，同样可以读取
+func LoadCommentEvent(path string) (*source.CommentEvent, error) {
+	if path == "" {
+		return nil, errors.NewConfigError("未设置 GITHUB_EVENT_PATH，无法读取评论事件")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewSystemError("读取评论事件失败", err)
+	}
+
+	var event github.IssueCommentEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, errors.NewSystemError("解析评论事件失败", err)
+	}
+	if event.Issue == nil || event.Comment == nil {
+		return nil, errors.NewValidationError("事件中缺少 Issue 或评论")
+	}
+
+	return ToCommentEvent(&event), nil
+}
+
+// ToCommentEvent 将 GitHub Issue 评论事件转换为与来源无关的评论事件
+func ToCommentEvent(event *github.IssueCommentEvent) *source.CommentEvent {
+	return &source.CommentEvent{
+		Action:  event.GetAction(),
+		Request: ToRequest(event.GetIssue()),
+		Comment: toComment(event.GetComment()),
+	}
+}
+
+// maintainerAssociations 可以对任意 Issue 执行命令的评论者身份
+var maintainerAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+// CanCommand 检查评论者是否有权对 Issue 执行命令，只有 Issue 作者和维护者可以执行
+func CanCommand(event *source.CommentEvent) bool {
+	if event.Comment.Maintainer {
+		return true
+	}
+	author := event.Request.Author
+	return author != "" && author == event.Comment.Author
+}
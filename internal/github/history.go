@@ -0,0 +1,156 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+
+	"sync-image/internal/source"
+	"sync-image/pkg/errors"
+)
+
+// maxHistoryIssues 查找历史同步记录时最多检查的 Issue 数量
+const maxHistoryIssues = 10
+
+// SyncRecord 一次成功同步的记录，以隐藏标记写入结果评论，用于识别重复请求
+type SyncRecord struct {
+	Issue    int       `json:"-"`               // 记录所在的 Issue
+	At       time.Time `json:"-"`               // 记录所在评论的发布时间
+	Source   string    `json:"source"`          // 标准化后的源镜像
+	Target   string    `json:"target"`          // 目标镜像
+	Platform string    `json:"platform"`        // 请求的架构，逗号分隔
+	Digest   string    `json:"digest"`          // 同步时上游镜像的清单摘要
+	Bytes    int64     `json:"bytes,omitempty"` // 同步的数据量，启用每日数据量限制时记录
+}
+
+// runMarker 每次处理 Issue 的结果评论中的隐藏标记，用于统计请求者的处理次数
+const runMarker = "<!-- sync-image:run -->"
+
+// recordMarkerRegexp 匹配结果评论中的同步记录标记
+var recordMarkerRegexp = regexp.MustCompile(`<!-- sync-image:record (\{.*?\}) -->`)
+
+// FormatSyncRecord 将同步记录格式化为 Markdown 中不可见的 HTML 注释
+func FormatSyncRecord(record SyncRecord) string {
+	data, _ := json.Marshal(record)
+	return fmt.Sprintf("<!-- sync-image:record %s -->", data)
+}
+
+// ParseSyncRecords 解析评论中的所有同步记录
+func ParseSyncRecords(body string) []SyncRecord {
+	var records []SyncRecord
+	for _, match := range recordMarkerRegexp.FindAllStringSubmatch(body, -1) {
+		var record SyncRecord
+		if err := json.Unmarshal([]byte(match[1]), &record); err == nil && record.Source != "" {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// SearchClosed 搜索评论中提到 text 的已关闭 Issue，按更新时间从新到旧排序
+func (c *DefaultClient) SearchClosed(ctx context.Context, label, text string, limit int) ([]*source.Request, error) {
+	query := fmt.Sprintf(`repo:%s/%s is:issue is:closed label:%s in:comments "%s"`,
+		c.config.User, c.config.Repo, label, text)
+	c.logger.Debug("搜索 Issues: %s", query)
+
+	result, _, err := c.client.Search.Issues(ctx, query, &github.SearchOptions{
+		Sort:        "updated",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: limit},
+	})
+	if err != nil {
+		return nil, errors.NewGitHubError("搜索历史同步记录失败", err)
+	}
+
+	requests := make([]*source.Request, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		requests = append(requests, ToRequest(issue))
+	}
+	return requests, nil
+}
+
+// FindSyncRecords 在已关闭的 success Issue 中查找源镜像的同步记录，按 Issue 更新时间从新到旧排序
+// 先通过请求来源的搜索找到提到源镜像的 Issue，再从评论的隐藏标记中读取记录
+func (p *IssueProcessor) FindSyncRecords(ctx context.Context, sourceImage string) ([]SyncRecord, error) {
+	p.logger.Debug("查找历史同步记录: %s", sourceImage)
+
+	requests, err := p.client.SearchClosed(ctx, "success", sourceImage, maxHistoryIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []SyncRecord
+	for _, request := range requests {
+		issueRecords, err := p.ListSyncRecords(ctx, request.Number)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range issueRecords {
+			if record.Source == sourceImage {
+				records = append(records, record)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// ListSyncRecords 读取 Issue 评论中的所有同步记录，较新的评论在前
+func (p *IssueProcessor) ListSyncRecords(ctx context.Context, number int) ([]SyncRecord, error) {
+	comments, err := p.ownComments(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return commentRecords(number, comments), nil
+}
+
+// ownComments 返回 Issue 中本工具发布的评论，较新的评论在前
+// 任何人都可以在评论中写入隐藏标记，同步记录和运行标记只从这些评论中读取
+func (p *IssueProcessor) ownComments(ctx context.Context, number int) ([]*source.Comment, error) {
+	comments, err := p.client.ListComments(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	var own []*source.Comment
+	for i := len(comments) - 1; i >= 0; i-- {
+		if p.isOwnComment(ctx, comments[i]) {
+			own = append(own, comments[i])
+		}
+	}
+	return own, nil
+}
+
+// commentRecords 解析评论中的同步记录
+func commentRecords(number int, comments []*source.Comment) []SyncRecord {
+	var records []SyncRecord
+	for _, comment := range comments {
+		for _, record := range ParseSyncRecords(comment.Body) {
+			record.Issue, record.At = number, comment.CreatedAt
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// isOwnComment 检查评论是否由本工具发布
+// 能获取当前认证用户时只认该用户的评论；GITHUB_TOKEN 和 GitHub App 无法获取当前用户，此时只认机器人的评论
+func (p *IssueProcessor) isOwnComment(ctx context.Context, comment *source.Comment) bool {
+	p.identityOnce.Do(func() {
+		identity, err := p.client.Identity(ctx)
+		if err != nil {
+			p.logger.Debug("无法获取当前用户，只读取机器人评论中的同步记录: %v", err)
+			return
+		}
+		p.identity = identity
+	})
+
+	if p.identity != "" {
+		return comment.Author == p.identity
+	}
+	return comment.Bot
+}
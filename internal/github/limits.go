@@ -0,0 +1,298 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+
+	"sync-image/internal/source"
+	"sync-image/pkg/errors"
+	"sync-image/pkg/utils"
+)
+
+// LabelRateLimited 请求者超出限制时添加的标签
+const LabelRateLimited = "rate-limited"
+
+// limitWindow 请求数和数据量限额的统计窗口
+const limitWindow = 24 * time.Hour
+
+// LimitError 请求者超出限制，Issue 已评论说明原因并关闭
+type LimitError struct {
+	User    string
+	Reason  string
+	RetryAt time.Time // 可以重新提交的时间，为零值表示需要联系维护者
+}
+
+// Error 实现 error 接口
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("用户 %s 的请求未处理: %s", e.User, e.Reason)
+}
+
+// LimitCheckError 检查请求者限制失败，Issue 未处理并保持打开，下次运行时重新处理
+type LimitCheckError struct {
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *LimitCheckError) Error() string {
+	return fmt.Sprintf("检查请求者限制失败: %v", e.Err)
+}
+
+// Unwrap 返回检查失败的原因
+func (e *LimitCheckError) Unwrap() error {
+	return e.Err
+}
+
+// RequesterRequests 获取用户在 since 之后创建或更新过的搬运请求，包括已关闭的 Issue
+func (c *DefaultClient) RequesterRequests(ctx context.Context, user string, since time.Time) ([]*source.Request, error) {
+	requests, err := c.listIssues(ctx, &github.IssueListByRepoOptions{
+		State:       "all",
+		Labels:      []string{source.LabelRequest},
+		Creator:     user,
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, errors.NewGitHubError(fmt.Sprintf("获取用户 %s 的 Issues 失败", user), err)
+	}
+	return requests, nil
+}
+
+// IsMember 检查用户是否为组织成员，team 不为空时检查是否为组织中该团队的成员
+// 成员关系不公开时需要 Token 有读取组织成员的权限
+func (c *DefaultClient) IsMember(ctx context.Context, org, team, user string) (bool, error) {
+	if team == "" {
+		member, _, err := c.client.Organizations.IsMember(ctx, org, user)
+		if err != nil {
+			return false, errors.NewGitHubError(fmt.Sprintf("检查用户 %s 是否为组织 %s 成员失败", user, org), err)
+		}
+		return member, nil
+	}
+
+	membership, resp, err := c.client.Teams.GetTeamMembershipBySlug(ctx, org, team, user)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errors.NewGitHubError(fmt.Sprintf("检查用户 %s 是否为团队 %s/%s 成员失败", user, org, team), err)
+	}
+	return membership.GetState() == "active", nil
+}
+
+// memberChecker 可以检查组织和团队成员关系的请求来源，目前只有 GitHub 支持
+type memberChecker interface {
+	IsMember(ctx context.Context, org, team, user string) (bool, error)
+}
+
+// CheckRequester 检查 Issue 作者是否可以提交请求，超出限制时返回 LimitError
+// 维护者只受禁止名单限制。每日请求数统计 24 小时内处理过的次数（包括当前 Issue 之前的处理和每次重试）
+// 以及排在当前 Issue 之前的待处理请求；处理次数和数据量只从本工具发布的结果评论中读取
+func (p *IssueProcessor) CheckRequester(ctx context.Context, request *source.Request) (*LimitError, error) {
+	limits := &p.config.Limits
+	user := request.Author
+
+	for _, denied := range limits.DenyUsers {
+		if strings.EqualFold(strings.TrimSpace(denied), user) {
+			return &LimitError{User: user, Reason: "你的账号暂时不能通过本仓库搬运镜像"}, nil
+		}
+	}
+	if request.Maintainer {
+		return nil, nil
+	}
+
+	if len(limits.AllowOrgs) > 0 || len(limits.AllowTeams) > 0 {
+		allowed, err := p.isAllowed(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			groups := append(append([]string{}, limits.AllowOrgs...), limits.AllowTeams...)
+			return &LimitError{User: user, Reason: fmt.Sprintf("本仓库目前只接受 %s 成员提交的请求", strings.Join(groups, "、"))}, nil
+		}
+	}
+
+	bytesLimit := limits.BytesLimit()
+	if limits.RequestsPerDay == 0 && bytesLimit == 0 && limits.FailureCooldown == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	windowStart := now.Add(-limitWindow)
+	since := windowStart
+	if cooldownStart := now.Add(-limits.FailureCooldown); cooldownStart.Before(since) {
+		since = cooldownStart
+	}
+	others, err := p.client.RequesterRequests(ctx, user, since)
+	if err != nil {
+		return nil, err
+	}
+	if !containsRequest(others, request.Number) {
+		others = append(others, request)
+	}
+
+	// requests 为计入每日请求数的时间，usage 为计入每日数据量的同步时间
+	var requests, usage []time.Time
+	var used int64
+	var lastFailure *source.Request
+	for _, other := range others {
+		if other.Number != request.Number && other.HasLabel("failed") && !other.IsOpen() &&
+			now.Sub(other.ClosedAt) < limits.FailureCooldown &&
+			(lastFailure == nil || other.ClosedAt.After(lastFailure.ClosedAt)) {
+			lastFailure = other
+		}
+
+		// 排在当前 Issue 之前、尚未处理的请求
+		if other.IsOpen() && other.Number != request.Number && other.CreatedAt.After(windowStart) &&
+			other.CreatedAt.Before(request.CreatedAt) && !other.HasLabel(LabelRateLimited) && !other.HasLabel("cancelled") {
+			requests = append(requests, other.CreatedAt)
+		}
+
+		if limits.RequestsPerDay == 0 && bytesLimit == 0 {
+			continue
+		}
+		comments, err := p.ownComments(ctx, other.Number)
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			if comment.CreatedAt.After(windowStart) && strings.Contains(comment.Body, runMarker) {
+				requests = append(requests, comment.CreatedAt)
+			}
+		}
+		for _, record := range commentRecords(other.Number, comments) {
+			if record.At.After(windowStart) && record.Bytes > 0 {
+				used += record.Bytes
+				usage = append(usage, record.At)
+			}
+		}
+	}
+
+	if lastFailure != nil {
+		return &LimitError{
+			User:    user,
+			Reason:  fmt.Sprintf("你的请求 #%d 刚刚同步失败，%s 内暂不处理新的请求，请先确认镜像名称和架构是否正确", lastFailure.Number, formatDuration(limits.FailureCooldown)),
+			RetryAt: lastFailure.ClosedAt.Add(limits.FailureCooldown),
+		}, nil
+	}
+
+	if limits.RequestsPerDay > 0 && len(requests) >= limits.RequestsPerDay {
+		return &LimitError{
+			User:    user,
+			Reason:  fmt.Sprintf("你在 24 小时内的请求已处理 %d 次（包括重试），达到每日上限 %d 次", len(requests), limits.RequestsPerDay),
+			RetryAt: earliest(requests).Add(limitWindow),
+		}, nil
+	}
+
+	if bytesLimit > 0 && used >= bytesLimit {
+		return &LimitError{
+			User:    user,
+			Reason:  fmt.Sprintf("你在 24 小时内已同步 %s，达到每日上限 %s", utils.FormatBytes(used), utils.FormatBytes(bytesLimit)),
+			RetryAt: earliest(usage).Add(limitWindow),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// RejectIssue 以评论说明请求者超出的限制，添加 rate-limited 标签并关闭 Issue
+func (p *IssueProcessor) RejectIssue(ctx context.Context, request *source.Request, status *StatusComment, limit *LimitError) error {
+	p.logger.Info("Issue #%d 未处理: %s", request.Number, limit.Error())
+
+	var b strings.Builder
+	b.WriteString("**⏸️ 请求未处理**\n\n")
+	b.WriteString(fmt.Sprintf("@%s 感谢你的请求！为了防止滥用，本仓库对每位用户的搬运请求有一定限制：\n\n", limit.User))
+	b.WriteString(fmt.Sprintf("> %s\n\n", limit.Reason))
+	if !limit.RetryAt.IsZero() {
+		b.WriteString(fmt.Sprintf("请在 %s（UTC）之后评论 `/retry` 重新处理本 Issue。", limit.RetryAt.UTC().Format("2006-01-02 15:04")))
+	}
+	b.WriteString("如有疑问请联系仓库维护者。\n")
+
+	if status == nil {
+		status = p.NewStatusComment(request)
+	}
+	if err := status.Finish(ctx, b.String()); err != nil {
+		p.logger.Error("添加限制说明评论失败: %v", err)
+	}
+	if err := p.client.AddLabels(ctx, request, []string{LabelRateLimited}); err != nil {
+		p.logger.Error("添加标签失败: %v", err)
+	}
+	if err := p.client.CloseRequest(ctx, request); err != nil {
+		p.logger.Error("关闭 Issue 失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeferIssue 检查请求者限制失败时以评论说明 Issue 暂未处理，Issue 保持打开以便下次运行时重新处理
+// 说明评论不带运行标记，不计入请求者的每日请求数
+func (p *IssueProcessor) DeferIssue(ctx context.Context, request *source.Request, status *StatusComment, cause error) {
+	p.logger.Warn("Issue #%d 暂不处理，检查请求者限制失败: %v", request.Number, cause)
+
+	if status == nil {
+		status = p.NewStatusComment(request)
+	}
+	message := "**⏸️ 请求暂未处理**\n\n暂时无法确认请求者是否超出限制，本 Issue 保持打开，将在下次运行时重新处理。\n"
+	if err := status.Finish(ctx, message); err != nil {
+		p.logger.Error("添加暂不处理说明评论失败: %v", err)
+	}
+}
+
+// isAllowed 检查用户是否为允许名单中任一组织或团队的成员
+func (p *IssueProcessor) isAllowed(ctx context.Context, user string) (bool, error) {
+	members, ok := p.client.(memberChecker)
+	if !ok {
+		return false, fmt.Errorf("请求来源 %s 不支持检查组织成员", p.client.Type())
+	}
+
+	for _, org := range p.config.Limits.AllowOrgs {
+		member, err := members.IsMember(ctx, strings.TrimSpace(org), "", user)
+		if err != nil || member {
+			return member, err
+		}
+	}
+	for _, team := range p.config.Limits.AllowTeams {
+		org, slug, _ := strings.Cut(strings.TrimSpace(team), "/")
+		member, err := members.IsMember(ctx, org, slug, user)
+		if err != nil || member {
+			return member, err
+		}
+	}
+	return false, nil
+}
+
+// formatDuration 格式化时长，省略末尾为 0 的分和秒，如 2h、1h30m
+func formatDuration(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// earliest 返回最早的时间
+func earliest(times []time.Time) time.Time {
+	var first time.Time
+	for _, t := range times {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
+
+// containsRequest 检查请求列表中是否有指定编号的请求
+func containsRequest(requests []*source.Request, number int) bool {
+	for _, request := range requests {
+		if request.Number == number {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sync-image/internal/source"
+	"sync-image/pkg/logger"
+)
+
+// statusEditInterval 同一阶段内更新进度的最小间隔，避免频繁调用 API
+const statusEditInterval = 10 * time.Second
+
+// StatusComment Issue 的状态评论，处理过程中每个阶段都更新同一条评论，完成后替换为结果
+// 方法可以在 nil 上调用，此时不做任何事
+type StatusComment struct {
+	client   source.RequestSource
+	request  *source.Request
+	header   string
+	logger   logger.Logger
+	mu       sync.Mutex
+	id       int64    // 评论 ID，为 0 表示尚未创建
+	stages   []string // 已完成的阶段，带时间戳
+	progress string   // 当前阶段的进度，新阶段开始时清空
+	edited   time.Time
+}
+
+// NewStatusComment 创建 Issue 的状态评论，第一次更新时才会发布
+func (p *IssueProcessor) NewStatusComment(request *source.Request) *StatusComment {
+	header := "**⏳ 同步中**"
+	if p.config.RunID != "" {
+		header += fmt.Sprintf(" · [构建进展](https://github.com/%s/%s/actions/runs/%s)",
+			p.config.User, p.config.Repo, p.config.RunID)
+	}
+
+	return &StatusComment{
+		client:  p.client,
+		request: request,
+		header:  header,
+		logger:  p.logger,
+	}
+}
+
+// Stage 记录新的处理阶段并立即更新评论
+func (c *StatusComment) Stage(ctx context.Context, format string, args ...interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stages = append(c.stages, fmt.Sprintf("`%s` %s", time.Now().UTC().Format("15:04:05"), fmt.Sprintf(format, args...)))
+	c.progress = ""
+	c.flush(ctx, c.render())
+}
+
+// Progress 更新当前阶段的进度，距上次更新不足 statusEditInterval 时只记录不发布
+func (c *StatusComment) Progress(ctx context.Context, format string, args ...interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.progress = fmt.Sprintf(format, args...)
+	if time.Since(c.edited) >= statusEditInterval {
+		c.flush(ctx, c.render())
+	}
+}
+
+// Finish 将状态评论替换为最终结果，状态评论无法修改时发布新评论
+func (c *StatusComment) Finish(ctx context.Context, result string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.id != 0 {
+		err := c.client.EditComment(ctx, c.request, c.id, result)
+		if err == nil {
+			return nil
+		}
+		c.logger.Warn("更新状态评论失败，改为发布新评论: %v", err)
+	}
+	_, err := c.client.CreateComment(ctx, c.request, result)
+	return err
+}
+
+// render 渲染状态评论的内容
+func (c *StatusComment) render() string {
+	var b strings.Builder
+	b.WriteString(c.header)
+	b.WriteString("\n\n")
+	for _, stage := range c.stages {
+		b.WriteString("- " + stage + "\n")
+	}
+	if c.progress != "" {
+		b.WriteString("- " + c.progress + "\n")
+	}
+	b.WriteString("\n> 时间为 UTC，本评论会随处理进度更新，完成后替换为同步结果\n")
+	return b.String()
+}
+
+// flush 发布或更新状态评论，失败只输出警告
+func (c *StatusComment) flush(ctx context.Context, body string) {
+	c.edited = time.Now()
+
+	if c.id == 0 {
+		id, err := c.client.CreateComment(ctx, c.request, body)
+		if err != nil {
+			c.logger.Warn("发布状态评论失败: %v", err)
+			return
+		}
+		c.id = id
+		return
+	}
+
+	if err := c.client.EditComment(ctx, c.request, c.id, body); err != nil {
+		c.logger.Warn("更新状态评论失败: %v", err)
+	}
+}
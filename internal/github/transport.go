@@ -0,0 +1,334 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sync-image/pkg/logger"
+)
+
+const (
+	// maxAttempts 单个请求最多发送的次数
+	maxAttempts = 4
+	// maxRateLimitWait 等待限流解除的最长时间，超过时直接返回限流错误
+	maxRateLimitWait = 15 * time.Minute
+	// secondaryRateLimitWait 次级限流没有返回 Retry-After 时的等待时间
+	secondaryRateLimitWait = time.Minute
+	// maxCachedResponses 条件请求最多缓存的响应数量
+	maxCachedResponses = 256
+)
+
+// cachedResponse 带 ETag 的 GET 响应，用于条件请求
+type cachedResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// Transport 感知 GitHub API 限流的 http.RoundTripper
+//   - 主限流：剩余次数为 0 时等待 X-RateLimit-Reset 后重试，之后的请求在重置前先等待
+//   - 次级限流：按 Retry-After 等待后重试，没有时等待一分钟
+//   - 幂等请求的 500、502、503、504 和网络错误按指数退避重试
+//   - GET 请求使用 ETag 条件请求，未变化时返回缓存的响应，不消耗限流次数
+type Transport struct {
+	base   http.RoundTripper
+	logger logger.Logger
+
+	mu     sync.Mutex
+	resets map[string]time.Time // 主限流次数用尽的资源（core、search 等）及其重置时间
+	cache  map[string]*cachedResponse
+
+	// now 和 sleep 可替换，便于测试
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewTransport 创建感知限流的 Transport，base 为空时使用 http.DefaultTransport
+func NewTransport(base http.RoundTripper, log logger.Logger) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:   base,
+		logger: log,
+		resets: make(map[string]time.Time),
+		cache:  make(map[string]*cachedResponse),
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := cacheKey(req)
+	cached := t.cached(key)
+
+	for attempt := 1; ; attempt++ {
+		if err := t.waitForReset(ctx, req); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := t.prepare(req, attempt, cached)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			if attempt >= maxAttempts || !idempotent(req) || ctx.Err() != nil {
+				return nil, err
+			}
+			delay := backoff(attempt)
+			t.logger.Warn("GitHub API 请求失败，%s 后重试: %s %s: %v", delay, req.Method, req.URL.Path, err)
+			if err := t.sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		t.updateRateLimit(req, resp)
+
+		delay, retry := t.retryDelay(req, resp, attempt)
+		if !retry {
+			// go-github 记录到次数用尽时会在重置前直接拒绝之后的请求，改由 waitForReset 等待
+			if resp.StatusCode < http.StatusBadRequest && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				resp.Header.Del("X-RateLimit-Reset")
+			}
+			return t.complete(key, cached, resp)
+		}
+		resp.Body.Close()
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// prepare 为第 attempt 次发送复制请求，重试时重新获取请求体，有缓存时带上 If-None-Match
+func (t *Transport) prepare(req *http.Request, attempt int, cached *cachedResponse) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("请求体无法重复读取，不能重试: %s %s", req.Method, req.URL.Path)
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	if cached != nil && clone.Header.Get("If-None-Match") == "" {
+		clone.Header.Set("If-None-Match", cached.etag)
+	}
+	return clone, nil
+}
+
+// retryDelay 判断响应是否需要重试以及重试前的等待时间
+func (t *Transport) retryDelay(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	if attempt >= maxAttempts {
+		return 0, false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		// 主限流：剩余次数为 0，等待重置
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			wait := t.resetAt(resp).Sub(t.now()) + time.Second
+			if wait > maxRateLimitWait {
+				t.logger.Warn("GitHub API 限流需要等待 %s，超过上限 %s，不再重试", wait.Round(time.Second), maxRateLimitWait)
+				return 0, false
+			}
+			t.logger.Warn("GitHub API 请求次数已用尽，等待 %s 后重试", wait.Round(time.Second))
+			return wait, true
+		}
+
+		// 次级限流：按 Retry-After 等待
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			seconds, err := strconv.Atoi(retryAfter)
+			if err != nil || time.Duration(seconds)*time.Second > maxRateLimitWait {
+				return 0, false
+			}
+			wait := time.Duration(seconds) * time.Second
+			t.logger.Warn("触发 GitHub API 次级限流，等待 %s 后重试", wait)
+			return wait, true
+		}
+		if isSecondaryRateLimit(resp) {
+			t.logger.Warn("触发 GitHub API 次级限流，等待 %s 后重试", secondaryRateLimitWait)
+			return secondaryRateLimitWait, true
+		}
+
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// 网关错误时请求可能已经执行，非幂等请求重试会重复创建评论和回应
+		if idempotent(req) {
+			delay := backoff(attempt)
+			t.logger.Warn("GitHub API 返回 %d，%s 后重试: %s %s", resp.StatusCode, delay, req.Method, req.URL.Path)
+			return delay, true
+		}
+	}
+
+	return 0, false
+}
+
+// complete 处理最终响应：304 返回缓存的内容，带 ETag 的 200 响应写入缓存
+func (t *Transport) complete(key string, cached *cachedResponse, resp *http.Response) (*http.Response, error) {
+	if key == "" {
+		return resp, nil
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.logger.Debug("GitHub API 响应未变化，使用缓存: %s", resp.Request.URL.Path)
+
+		header := cached.header.Clone()
+		// 限流信息以最新的响应为准
+		for name, values := range resp.Header {
+			if strings.HasPrefix(name, "X-Ratelimit-") {
+				header[name] = values
+			}
+		}
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.body)),
+			ContentLength: int64(len(cached.body)),
+			Request:       resp.Request,
+		}, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	if len(t.cache) >= maxCachedResponses {
+		t.cache = make(map[string]*cachedResponse)
+	}
+	t.cache[key] = &cachedResponse{etag: etag, header: resp.Header.Clone(), body: body}
+	t.mu.Unlock()
+
+	return resp, nil
+}
+
+// cached 返回请求对应的缓存响应
+func (t *Transport) cached(key string) *cachedResponse {
+	if key == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cache[key]
+}
+
+// updateRateLimit 记录响应中的主限流信息，次数用尽时同一资源之后的请求在重置前等待
+func (t *Transport) updateRateLimit(req *http.Request, resp *http.Response) {
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return
+	}
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = rateLimitResource(req)
+	}
+	t.logger.Debug("GitHub API %s 剩余请求次数: %s", resource, remaining)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if remaining == "0" {
+		t.resets[resource] = t.resetAt(resp)
+	} else {
+		delete(t.resets, resource)
+	}
+}
+
+// waitForReset 请求所属资源的主限流次数已用尽时等待重置，等待时间超过上限时不等待，由 GitHub 返回限流错误
+func (t *Transport) waitForReset(ctx context.Context, req *http.Request) error {
+	t.mu.Lock()
+	wait := t.resets[rateLimitResource(req)].Sub(t.now())
+	t.mu.Unlock()
+
+	if wait <= 0 || wait > maxRateLimitWait {
+		return nil
+	}
+	t.logger.Warn("GitHub API 请求次数已用尽，等待 %s 后继续", wait.Round(time.Second))
+	return t.sleep(ctx, wait+time.Second)
+}
+
+// resetAt 返回响应中的限流重置时间，缺少时按一分钟后计算
+func (t *Transport) resetAt(resp *http.Response) time.Time {
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return time.Unix(reset, 0)
+	}
+	return t.now().Add(time.Minute)
+}
+
+// isSecondaryRateLimit 检查 403 响应是否为次级限流，读取的响应体会放回
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// rateLimitResource 返回请求计入的限流资源，搜索接口单独计算
+func rateLimitResource(req *http.Request) string {
+	if strings.Contains(req.URL.Path, "/search/") {
+		return "search"
+	}
+	return "core"
+}
+
+// cacheKey 返回可以使用条件请求的缓存键，只缓存 GET 请求
+func cacheKey(req *http.Request) string {
+	if req.Method != http.MethodGet {
+		return ""
+	}
+	return req.Header.Get("Accept") + " " + req.URL.String()
+}
+
+// idempotent 请求是否可以安全重试
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// backoff 返回第 attempt 次失败后的等待时间：1s、2s、4s
+func backoff(attempt int) time.Duration {
+	return time.Second << (attempt - 1)
+}
+
+// sleepContext 等待 d，context 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
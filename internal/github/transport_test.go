@@ -0,0 +1,187 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sync-image/pkg/logger"
+)
+
+// newTestTransport 创建使用模拟时钟的 Transport，等待时不会真正休眠，只记录等待时长并推进时钟
+func newTestTransport(now time.Time) (*Transport, *[]time.Duration) {
+	var waits []time.Duration
+	transport := NewTransport(nil, logger.NewLogger("error"))
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return nil
+	}
+	return transport, &waits
+}
+
+// send 通过 transport 发送请求并返回响应内容
+func send(t *testing.T, transport *Transport, method, url, body string) (*http.Response, string) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func TestTransportWaitsForPrimaryRateLimitReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(30*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport, waits := newTestTransport(now)
+	resp, body := send(t, transport, http.MethodGet, server.URL+"/repos/o/r/issues", "")
+
+	if resp.StatusCode != http.StatusOK || body != "ok" {
+		t.Fatalf("got %d %q, want 200 \"ok\"", resp.StatusCode, body)
+	}
+	if calls != 2 {
+		t.Fatalf("got %d requests, want 2", calls)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 31*time.Second {
+		t.Fatalf("got waits %v, want [31s]", *waits)
+	}
+}
+
+func TestTransportRespectsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	transport, waits := newTestTransport(time.Now())
+	// 次级限流时请求没有执行，POST 也会重试
+	resp, _ := send(t, transport, http.MethodPost, server.URL+"/repos/o/r/issues/1/comments", `{"body":"hi"}`)
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("got %d, want 201", resp.StatusCode)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+		t.Fatalf("got waits %v, want [7s]", *waits)
+	}
+}
+
+func TestTransportRetriesServerErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		wantCalls int32
+		wantCode  int
+	}{
+		{"GET 502", http.MethodGet, http.StatusBadGateway, 3, http.StatusOK},
+		{"GET 500", http.MethodGet, http.StatusInternalServerError, 3, http.StatusOK},
+		{"POST 502", http.MethodPost, http.StatusBadGateway, 1, http.StatusBadGateway},
+		{"POST 503", http.MethodPost, http.StatusServiceUnavailable, 1, http.StatusServiceUnavailable},
+		{"POST 504", http.MethodPost, http.StatusGatewayTimeout, 1, http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			body := ""
+			if tt.method == http.MethodPost {
+				body = `{"body":"hi"}`
+			}
+			transport, waits := newTestTransport(time.Now())
+			resp, _ := send(t, transport, tt.method, server.URL+"/repos/o/r/issues/1/comments", body)
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d requests, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 3 && (len(*waits) != 2 || (*waits)[0] != time.Second || (*waits)[1] != 2*time.Second) {
+				t.Errorf("got waits %v, want [1s 2s]", *waits)
+			}
+		})
+	}
+}
+
+func TestTransportReplaysCachedResponseOnNotModified(t *testing.T) {
+	const etag = `"abc"`
+	var calls, conditional int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"number":1}]`))
+	}))
+	defer server.Close()
+
+	transport, _ := newTestTransport(time.Now())
+	url := server.URL + "/repos/o/r/issues"
+	_, first := send(t, transport, http.MethodGet, url, "")
+	resp, second := send(t, transport, http.MethodGet, url, "")
+
+	if calls != 2 || conditional != 1 {
+		t.Fatalf("got %d requests (%d conditional), want 2 (1 conditional)", calls, conditional)
+	}
+	if resp.StatusCode != http.StatusOK || second != first {
+		t.Fatalf("got %d %q, want 200 %q", resp.StatusCode, second, first)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q from cache, want application/json", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Package gitops 解析声明式镜像清单 images.yaml
+//
+// 清单列出需要保持同步的镜像、标签、架构和目标仓库，reconcile 命令每次运行时
+// 比较上游和目标仓库，只同步缺失或已过期的镜像。清单文件可以提交到仓库中，
+// 通过代码评审维护基础镜像集合，代替逐个提交 Issue。
+package gitops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"sync-image/pkg/utils"
+)
+
+// Manifest 声明式镜像清单
+type Manifest struct {
+	Defaults Defaults    `yaml:"defaults"`
+	Images   []ImageSpec `yaml:"images"`
+}
+
+// Defaults 镜像条目未指定时使用的默认值
+type Defaults struct {
+	Platforms   []string `yaml:"platforms"`   // 为空时使用配置中的 platforms
+	Destination string   `yaml:"destination"` // 为空时按转换规则选择目标仓库
+}
+
+// ImageSpec 清单中的单个镜像条目
+// 标签可以写在 image 中，也可以通过 tags 列出，或用 tag_filter 从上游标签中筛选
+type ImageSpec struct {
+	Image       string   `yaml:"image"`
+	Tags        []string `yaml:"tags"`
+	TagFilter   string   `yaml:"tag_filter"` // 匹配上游标签的正则表达式
+	Latest      int      `yaml:"latest"`     // 只保留 tag_filter 匹配结果中版本最新的 N 个，0 表示全部
+	Platforms   []string `yaml:"platforms"`
+	Destination string   `yaml:"destination"` // config 中 destinations 的名称，指定后忽略规则上的 destination
+	Target      string   `yaml:"target"`      // 目标仓库名称，替换规则和命名策略生成的名称
+
+	tagFilter *regexp.Regexp
+}
+
+// LoadManifest 读取并校验清单文件，destinations 为配置中定义的目标仓库名称
+func LoadManifest(path string, destinations []string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取镜像清单失败: %w", err)
+	}
+
+	var manifest Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("解析镜像清单 %s 失败: %w", path, err)
+	}
+	if err := manifest.Validate(destinations); err != nil {
+		return nil, fmt.Errorf("镜像清单 %s 无效: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Validate 校验清单中的镜像引用、标签筛选和目标仓库，并编译 tag_filter
+func (m *Manifest) Validate(destinations []string) error {
+	if len(m.Images) == 0 {
+		return fmt.Errorf("images 不能为空")
+	}
+
+	known := make(map[string]bool, len(destinations))
+	for _, name := range destinations {
+		known[name] = true
+	}
+	if m.Defaults.Destination != "" && !known[m.Defaults.Destination] {
+		return fmt.Errorf("defaults.destination 引用的目标仓库 %s 不存在", m.Defaults.Destination)
+	}
+
+	for i := range m.Images {
+		spec := &m.Images[i]
+		if err := spec.validate(known); err != nil {
+			return fmt.Errorf("images[%d] %s: %w", i, spec.Image, err)
+		}
+	}
+	return nil
+}
+
+// validate 校验单个镜像条目
+func (s *ImageSpec) validate(destinations map[string]bool) error {
+	ref, err := utils.ParseReference(s.Image)
+	if err != nil {
+		return err
+	}
+	if ref.Digest != "" {
+		return fmt.Errorf("不支持按摘要引用镜像，请使用标签")
+	}
+	if ref.Tag != "" && (len(s.Tags) > 0 || s.TagFilter != "") {
+		return fmt.Errorf("image 中已包含标签，不能同时指定 tags 或 tag_filter")
+	}
+
+	for _, tag := range s.Tags {
+		if _, err := utils.ParseReference(ref.Name() + ":" + tag); err != nil {
+			return fmt.Errorf("无效的标签 %q", tag)
+		}
+	}
+	if s.TagFilter != "" {
+		if s.tagFilter, err = regexp.Compile(s.TagFilter); err != nil {
+			return fmt.Errorf("无效的 tag_filter: %w", err)
+		}
+	}
+	if s.Latest < 0 {
+		return fmt.Errorf("latest 不能为负数")
+	}
+	if s.Latest > 0 && s.TagFilter == "" {
+		return fmt.Errorf("latest 只能与 tag_filter 一起使用")
+	}
+
+	if s.Destination != "" && !destinations[s.Destination] {
+		return fmt.Errorf("引用的目标仓库 %s 不存在", s.Destination)
+	}
+	if s.Target != "" {
+		if _, err := utils.ParseReference(s.Target); err != nil || strings.ContainsAny(s.Target, ":@") {
+			return fmt.Errorf("无效的目标仓库名称 %q", s.Target)
+		}
+	}
+	return nil
+}
+
+// Repository 返回不带标签的镜像名称
+func (s *ImageSpec) Repository() string {
+	ref, err := utils.ParseReference(s.Image)
+	if err != nil {
+		return s.Image
+	}
+	return ref.Name()
+}
+
+// NeedsTagList 返回是否需要读取上游标签列表来确定要同步的标签
+func (s *ImageSpec) NeedsTagList() bool {
+	return s.TagFilter != ""
+}
+
+// ResolveTags 返回条目要同步的标签，available 为上游标签列表，只在 NeedsTagList 时使用
+// 未指定任何标签时使用 latest
+func (s *ImageSpec) ResolveTags(available []string) []string {
+	if ref, err := utils.ParseReference(s.Image); err == nil && ref.Tag != "" {
+		return []string{ref.Tag}
+	}
+
+	tags := append([]string{}, s.Tags...)
+	if s.tagFilter != nil {
+		var matched []string
+		for _, tag := range available {
+			if s.tagFilter.MatchString(tag) {
+				matched = append(matched, tag)
+			}
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			return compareVersions(matched[i], matched[j]) > 0
+		})
+		if s.Latest > 0 && len(matched) > s.Latest {
+			matched = matched[:s.Latest]
+		}
+		tags = append(tags, matched...)
+	}
+	if len(tags) == 0 && s.TagFilter == "" {
+		tags = []string{"latest"}
+	}
+
+	// 去重并保持顺序
+	seen := make(map[string]bool, len(tags))
+	unique := tags[:0]
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// EffectivePlatforms 返回条目要同步的架构，依次使用条目、清单默认值和 fallback
+func (m *Manifest) EffectivePlatforms(spec *ImageSpec, fallback string) string {
+	if len(spec.Platforms) > 0 {
+		return strings.Join(spec.Platforms, ",")
+	}
+	if len(m.Defaults.Platforms) > 0 {
+		return strings.Join(m.Defaults.Platforms, ",")
+	}
+	return fallback
+}
+
+// EffectiveDestination 返回条目指定的目标仓库，为空表示按转换规则选择
+func (m *Manifest) EffectiveDestination(spec *ImageSpec) string {
+	if spec.Destination != "" {
+		return spec.Destination
+	}
+	return m.Defaults.Destination
+}
+
+// versionPartRegexp 将标签拆分为数字和非数字片段
+var versionPartRegexp = regexp.MustCompile(`\d+|\D+`)
+
+// compareVersions 按版本号比较两个标签，数字片段按数值比较，其余按字典序
+// 如 v1.10.0 > v1.9.3；一个标签是另一个的前缀时，多出的部分以 - 开头（如 -rc1）则较短的更新，否则较长的更新
+func compareVersions(a, b string) int {
+	partsA, partsB := versionPartRegexp.FindAllString(a, -1), versionPartRegexp.FindAllString(b, -1)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if partsA[i] == partsB[i] {
+			continue
+		}
+		numA, errA := strconv.ParseUint(partsA[i], 10, 64)
+		numB, errB := strconv.ParseUint(partsB[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA > numB {
+					return 1
+				}
+				return -1
+			}
+		case errA == nil:
+			return 1
+		case errB == nil:
+			return -1
+		default:
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+
+	switch {
+	case len(partsA) < len(partsB):
+		if strings.HasPrefix(partsB[len(partsA)], "-") {
+			return 1
+		}
+		return -1
+	case len(partsA) > len(partsB):
+		if strings.HasPrefix(partsA[len(partsB)], "-") {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,73 @@
+package mapping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileStore 将索引保存为本地 JSON 文件
+// 在 GitHub Actions 中使用时需要在工作流中提交该文件，否则记录不会保留到下一次运行
+type FileStore struct {
+	path string
+}
+
+// NewFileStore 创建文件存储后端
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load 读取索引文件，文件不存在时返回空索引
+func (s *FileStore) Load(ctx context.Context) (*Document, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return newDocument(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDocument(data)
+}
+
+// Save 写入索引文件，先写临时文件再重命名，避免中断时留下不完整的文件
+func (s *FileStore) Save(ctx context.Context, doc *Document) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// String 返回文件路径
+func (s *FileStore) String() string {
+	return s.path
+}
+
+// decodeDocument 解析并检查索引内容
+func decodeDocument(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析映射索引失败: %w", err)
+	}
+	if doc.Version != IndexVersion {
+		return nil, fmt.Errorf("不支持的映射索引版本: %d", doc.Version)
+	}
+	if doc.Mappings == nil {
+		doc.Mappings = make(map[string]Entry)
+	}
+	return &doc, nil
+}
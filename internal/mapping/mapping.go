@@ -0,0 +1,239 @@
+// Package mapping 维护源镜像仓库到目标镜像仓库的持久化映射索引
+//
+// 命名策略无法保证不同的上游仓库一定得到不同的目标名称，索引记录每个目标仓库
+// 归属的源仓库，新的同步请求会覆盖其他源仓库已占用的目标仓库时拒绝执行。
+// 索引可以保存为本地文件，也可以作为 OCI 制品保存在目标仓库中。
+package mapping
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sync-image/pkg/utils"
+)
+
+// IndexVersion 当前索引格式版本
+const IndexVersion = 1
+
+// Entry 单个目标仓库的映射记录
+type Entry struct {
+	Source  string    `json:"source"` // 标准化后的源仓库名称，不含标签
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Document 索引的持久化格式
+type Document struct {
+	Version  int              `json:"version"`
+	Updated  time.Time        `json:"updated"`
+	Mappings map[string]Entry `json:"mappings"` // 目标仓库名称（不含标签）-> 源仓库
+}
+
+// Store 索引的存储后端
+type Store interface {
+	// Load 读取索引，索引不存在时返回空索引
+	Load(ctx context.Context) (*Document, error)
+	// Save 保存索引
+	Save(ctx context.Context, doc *Document) error
+	// String 返回存储位置描述
+	String() string
+}
+
+// CollisionError 目标仓库已被其他源仓库占用
+type CollisionError struct {
+	Target         string
+	Source         string
+	ExistingSource string
+}
+
+// Error 实现 error 接口
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("目标仓库 %s 已被 %s 占用，%s 同步到该仓库会覆盖已有镜像，请调整命名策略或转换规则",
+		e.Target, e.ExistingSource, e.Source)
+}
+
+// Index 映射索引
+type Index struct {
+	store     Store
+	doc       *Document
+	redirects map[string]string
+	reserved  map[string]*reservation // 目标仓库名称 -> 正在同步的源仓库
+	mu        sync.Mutex
+}
+
+// reservation 正在同步、尚未记录到索引的目标仓库
+type reservation struct {
+	source string
+	count  int // 同一源仓库同时同步的镜像数量
+}
+
+// NewIndex 创建基于指定存储后端的映射索引，使用前需要调用 Load
+func NewIndex(store Store) *Index {
+	return &Index{
+		store:    store,
+		doc:      newDocument(),
+		reserved: make(map[string]*reservation),
+	}
+}
+
+// SetRedirects 设置上游仓库域名重定向表
+// 设置后按重定向后的名称比较和记录源仓库，重定向前后的请求视为同一个源仓库
+func (i *Index) SetRedirects(redirects map[string]string) {
+	i.redirects = redirects
+}
+
+// Load 从存储后端重新读取索引
+func (i *Index) Load(ctx context.Context) error {
+	doc, err := i.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("读取映射索引 %s 失败: %w", i.store, err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.doc = doc
+	return nil
+}
+
+// Check 检查源镜像同步到目标镜像是否会覆盖其他源仓库占用的目标仓库
+func (i *Index) Check(sourceImage, targetImage string) error {
+	source, target := i.sourceName(sourceImage), targetName(targetImage)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.checkEntry(i.doc, source, target)
+}
+
+// Reserve 在推送前为源镜像占用目标仓库，并发同步的其他源仓库不能再占用同一目标仓库
+// 目标仓库已被其他源仓库记录或占用时返回 CollisionError；推送完成并调用 Record 后或推送失败时调用 release 释放
+func (i *Index) Reserve(sourceImage, targetImage string) (release func(), err error) {
+	source, target := i.sourceName(sourceImage), targetName(targetImage)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err := i.checkEntry(i.doc, source, target); err != nil {
+		return nil, err
+	}
+
+	r, ok := i.reserved[target]
+	if !ok {
+		r = &reservation{source: source}
+		i.reserved[target] = r
+	}
+	r.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			i.mu.Lock()
+			defer i.mu.Unlock()
+			if r.count--; r.count == 0 {
+				delete(i.reserved, target)
+			}
+		})
+	}, nil
+}
+
+// Record 记录源镜像到目标镜像的映射并保存
+// 保存前会重新读取索引，以合并其他运行期间写入的记录
+func (i *Index) Record(ctx context.Context, sourceImage, targetImage string) error {
+	source, target := i.sourceName(sourceImage), targetName(targetImage)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	doc, err := i.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("读取映射索引 %s 失败: %w", i.store, err)
+	}
+	if err := i.checkEntry(doc, source, target); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	entry, ok := doc.Mappings[target]
+	if !ok {
+		entry = Entry{Source: source, Created: now}
+	}
+	entry.Updated = now
+	doc.Mappings[target] = entry
+	doc.Updated = now
+
+	if err := i.store.Save(ctx, doc); err != nil {
+		return fmt.Errorf("保存映射索引 %s 失败: %w", i.store, err)
+	}
+	i.doc = doc
+	return nil
+}
+
+// Targets 返回已记录的目标仓库，按名称排序
+func (i *Index) Targets() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	targets := make([]string, 0, len(i.doc.Mappings))
+	for target := range i.doc.Mappings {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// checkEntry 检查目标仓库的已有记录和正在同步的占用，调用时需要持有 i.mu
+func (i *Index) checkEntry(doc *Document, source, target string) error {
+	if r, ok := i.reserved[target]; ok && r.source != source {
+		return &CollisionError{Target: target, Source: source, ExistingSource: r.source}
+	}
+
+	entry, ok := doc.Mappings[target]
+	if !ok || entry.Source == source || i.sourceName(entry.Source) == source {
+		return nil
+	}
+	return &CollisionError{Target: target, Source: source, ExistingSource: entry.Source}
+}
+
+// newDocument 创建空索引
+func newDocument() *Document {
+	return &Document{
+		Version:  IndexVersion,
+		Mappings: make(map[string]Entry),
+	}
+}
+
+// sourceName 返回重定向后的源仓库名称
+func (i *Index) sourceName(image string) string {
+	upstream, _ := utils.RedirectReference(image, i.redirects)
+	return sourceName(upstream)
+}
+
+// sourceName 返回标准化后不含标签和摘要的源仓库名称
+func sourceName(image string) string {
+	if ref, err := utils.ParseNormalizedReference(image); err == nil {
+		return ref.Name()
+	}
+	return stripTag(image)
+}
+
+// targetName 返回不含标签和摘要的目标仓库名称
+func targetName(image string) string {
+	if ref, err := utils.ParseReference(image); err == nil {
+		return ref.Name()
+	}
+	return stripTag(image)
+}
+
+// stripTag 去掉无法解析的镜像名称中的标签和摘要
+func stripTag(image string) string {
+	image = strings.TrimSpace(image)
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
@@ -0,0 +1,43 @@
+package mapping
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MemoryStore 只保存在内存中的索引，用于离线检查规则时不修改实际索引
+type MemoryStore struct {
+	data []byte
+}
+
+// NewMemoryStore 创建内存存储后端，doc 为初始内容，为 nil 时从空索引开始
+func NewMemoryStore(doc *Document) *MemoryStore {
+	store := &MemoryStore{}
+	if doc != nil {
+		store.data, _ = json.Marshal(doc)
+	}
+	return store
+}
+
+// Load 返回索引的副本
+func (s *MemoryStore) Load(ctx context.Context) (*Document, error) {
+	if s.data == nil {
+		return newDocument(), nil
+	}
+	return decodeDocument(s.data)
+}
+
+// Save 保存索引的副本
+func (s *MemoryStore) Save(ctx context.Context, doc *Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.data = data
+	return nil
+}
+
+// String 返回存储位置描述
+func (s *MemoryStore) String() string {
+	return "memory"
+}
@@ -0,0 +1,125 @@
+package mapping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sync-image/internal/registry"
+)
+
+// 映射索引制品的媒体类型
+const (
+	ArtifactType       = "application/vnd.sync-image.mapping.v1"
+	MediaTypeIndexJSON = "application/vnd.sync-image.mapping.v1+json"
+	MediaTypeEmpty     = "application/vnd.oci.empty.v1+json"
+)
+
+// emptyConfig OCI 规范中的空 config 内容
+var emptyConfig = []byte("{}")
+
+// artifactManifest 带 artifactType 的 OCI 清单
+type artifactManifest struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	MediaType     string                `json:"mediaType"`
+	ArtifactType  string                `json:"artifactType"`
+	Config        registry.Descriptor   `json:"config"`
+	Layers        []registry.Descriptor `json:"layers"`
+}
+
+// RegistryStore 将索引作为 OCI 制品保存在镜像仓库中
+// 适用于 GitHub Actions 等每次运行都从干净环境开始的场景
+type RegistryStore struct {
+	client *registry.Client
+	ref    registry.ImageReference
+}
+
+// NewRegistryStore 创建仓库存储后端，reference 为制品的镜像引用，如 registry/namespace/sync-image-mapping:latest
+func NewRegistryStore(client *registry.Client, reference string) (*RegistryStore, error) {
+	ref, err := registry.ParseImageReference(reference)
+	if err != nil {
+		return nil, fmt.Errorf("无效的映射索引制品引用 %s: %w", reference, err)
+	}
+	return &RegistryStore{client: client, ref: ref}, nil
+}
+
+// Load 拉取索引制品，制品不存在时返回空索引
+func (s *RegistryStore) Load(ctx context.Context) (*Document, error) {
+	resp, err := s.client.GetManifest(ctx, s.ref)
+	if err == registry.ErrNotFound {
+		return newDocument(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest artifactManifest
+	if err := json.Unmarshal(resp.Data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析映射索引制品清单失败: %w", err)
+	}
+	if manifest.ArtifactType != ArtifactType || len(manifest.Layers) != 1 {
+		return nil, fmt.Errorf("%s 不是映射索引制品", s.ref)
+	}
+
+	reader, err := s.client.GetBlob(ctx, s.ref, manifest.Layers[0].Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDocument(data)
+}
+
+// Save 推送索引内容和制品清单
+func (s *RegistryStore) Save(ctx context.Context, doc *Document) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	config := registry.Descriptor{MediaType: MediaTypeEmpty, Digest: registry.Digest(emptyConfig), Size: int64(len(emptyConfig))}
+	layer := registry.Descriptor{MediaType: MediaTypeIndexJSON, Digest: registry.Digest(data), Size: int64(len(data))}
+
+	if err := s.pushBlob(ctx, config, emptyConfig); err != nil {
+		return err
+	}
+	if err := s.pushBlob(ctx, layer, data); err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(artifactManifest{
+		SchemaVersion: 2,
+		MediaType:     registry.MediaTypeOCIManifest,
+		ArtifactType:  ArtifactType,
+		Config:        config,
+		Layers:        []registry.Descriptor{layer},
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.client.PutManifest(ctx, s.ref, registry.MediaTypeOCIManifest, manifest)
+}
+
+// String 返回制品引用
+func (s *RegistryStore) String() string {
+	return s.ref.String()
+}
+
+// pushBlob 推送 blob，已存在时跳过
+func (s *RegistryStore) pushBlob(ctx context.Context, desc registry.Descriptor, data []byte) error {
+	exists, err := s.client.BlobExists(ctx, s.ref, desc.Digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return s.client.PushBlob(ctx, s.ref, desc.Digest, desc.Size, bytes.NewReader(data))
+}
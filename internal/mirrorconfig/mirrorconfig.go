@@ -0,0 +1,224 @@
+// Package mirrorconfig 根据转换规则和目标仓库生成容器运行时的镜像加速配置
+//
+// 运行时的镜像加速只能按仓库域名配置，并且只能在仓库路径前加上固定前缀。
+// 因此只有把某个域名下的所有仓库整体映射到目标仓库中同一前缀下的规则才能表示，
+// 其他规则（按仓库改写、扁平化命名、只保留最后一段等）会生成警告。
+package mirrorconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sync-image/internal/config"
+	"sync-image/internal/docker"
+	"sync-image/pkg/utils"
+)
+
+// DockerHubEndpoint Docker Hub 实际的 API 地址
+const DockerHubEndpoint = "registry-1.docker.io"
+
+// probeTag 探测用的镜像标签，用于检查标签是否原样保留
+const probeTag = "sync-image-probe"
+
+// Mirror 上游仓库域名到目标仓库前缀的映射
+// 上游的 <Upstream>/<path>:<tag> 对应目标的 <Registry>/<Prefix>/<path>:<tag>
+type Mirror struct {
+	Upstream string
+	Registry string
+	Prefix   string
+}
+
+// Location 返回镜像加速地址（不含协议）
+func (m Mirror) Location() string {
+	if m.Prefix == "" {
+		return m.Registry
+	}
+	return m.Registry + "/" + m.Prefix
+}
+
+// Resolve 探测每个上游仓库域名的转换结果，返回可以表示为镜像加速的映射和无法表示的规则警告
+// hosts 为额外需要检查的域名，规则中引用的域名和 docker.io 总会被检查
+func Resolve(transformer *docker.ImageTransformer, defaultTarget docker.Target, rules config.RuleList, hosts []string) ([]Mirror, []string) {
+	var mirrors []Mirror
+	var warnings []string
+	used := make(map[string]bool)
+
+	for _, host := range collectHosts(rules, hosts) {
+		mirror, applied, err := resolveHost(transformer, defaultTarget, host)
+		for _, rule := range applied {
+			used[rule] = true
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", host, err))
+			continue
+		}
+		mirrors = append(mirrors, mirror)
+	}
+
+	// 没有作用于任何探测镜像的规则只匹配特定仓库，无法按域名表示
+	for _, rule := range rules.RewriteRules() {
+		if !used[rule.DisplayName()] {
+			warnings = append(warnings, fmt.Sprintf("规则 %s 只匹配部分仓库，无法表示为镜像加速配置，其匹配的镜像需要使用 pull 命令拉取", rule.DisplayName()))
+		}
+	}
+
+	return mirrors, warnings
+}
+
+// resolveHost 用多个不同深度的仓库路径探测一个域名，所有路径都映射到同一目标前缀时返回该映射
+func resolveHost(transformer *docker.ImageTransformer, defaultTarget docker.Target, host string) (Mirror, []string, error) {
+	probes := []string{"probe", "org/probe", "org/team/probe"}
+	if host == utils.DefaultDomain {
+		probes = []string{"library/probe", "org/probe"}
+	}
+
+	var mirror Mirror
+	var applied []string
+	for i, probe := range probes {
+		source := host + "/" + probe + ":" + probeTag
+		result, err := transformer.Transform(source, defaultTarget)
+		if err != nil {
+			return Mirror{}, applied, err
+		}
+		applied = append(applied, result.Rules...)
+
+		target, err := utils.ParseReference(result.TargetImage)
+		if err != nil || target.Domain == "" {
+			return Mirror{}, applied, fmt.Errorf("目标镜像 %s 不包含仓库地址，请配置目标仓库", result.TargetImage)
+		}
+		if target.Tag != probeTag {
+			return Mirror{}, applied, fmt.Errorf("转换后镜像标签发生变化（%s -> %s），无法表示为镜像加速配置", source, result.TargetImage)
+		}
+
+		prefix, ok := pathPrefix(target.Path, probe)
+		if !ok {
+			return Mirror{}, applied, fmt.Errorf("转换后没有保留仓库路径（%s -> %s），无法表示为镜像加速配置，可以使用 full-path 命名策略", source, result.TargetImage)
+		}
+
+		current := Mirror{Upstream: host, Registry: target.Domain, Prefix: prefix}
+		if i > 0 && current != mirror {
+			return Mirror{}, applied, fmt.Errorf("不同仓库映射到了不同的目标前缀（%s 与 %s），无法表示为镜像加速配置", mirror.Location(), current.Location())
+		}
+		mirror = current
+	}
+
+	return mirror, applied, nil
+}
+
+// pathPrefix 返回目标路径中源仓库路径之前的前缀
+func pathPrefix(targetPath, sourcePath string) (string, bool) {
+	if targetPath == sourcePath {
+		return "", true
+	}
+	if strings.HasSuffix(targetPath, "/"+sourcePath) {
+		return strings.TrimSuffix(targetPath, "/"+sourcePath), true
+	}
+	return "", false
+}
+
+// hostPattern 规则开头的仓库域名，允许未转义的点号
+var hostPattern = regexp.MustCompile(`^\^((?:[a-zA-Z0-9-]|\\?\.)+(?::[0-9]+)?)`)
+
+// collectHosts 返回需要检查的域名：docker.io、规则开头引用的域名和额外指定的域名
+func collectHosts(rules config.RuleList, extra []string) []string {
+	seen := map[string]bool{utils.DefaultDomain: true}
+	hosts := []string{utils.DefaultDomain}
+	add := func(host string) {
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	for _, rule := range rules {
+		match := hostPattern.FindStringSubmatch(rule.Pattern)
+		if match == nil {
+			continue
+		}
+		host := strings.ReplaceAll(match[1], `\.`, ".")
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			add(host)
+		}
+	}
+	for _, host := range extra {
+		add(strings.TrimSuffix(strings.TrimSpace(host), "/"))
+	}
+
+	sort.Strings(hosts[1:])
+	return hosts
+}
+
+// ContainerdHosts 生成 containerd 的 certs.d/<host>/hosts.toml 文件，返回相对路径到内容的映射
+func ContainerdHosts(mirrors []Mirror) map[string][]byte {
+	files := make(map[string][]byte, len(mirrors))
+	for _, mirror := range mirrors {
+		server := mirror.Upstream
+		if server == utils.DefaultDomain {
+			server = DockerHubEndpoint
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# 由 sync-image mirrors 生成\nserver = %q\n\n", "https://"+server)
+		if mirror.Prefix == "" {
+			fmt.Fprintf(&buf, "[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", "https://"+mirror.Registry)
+		} else {
+			// override_path 使 containerd 直接使用给定的 API 路径，从而在仓库路径前加上前缀
+			fmt.Fprintf(&buf, "[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n  override_path = true\n",
+				"https://"+mirror.Registry+"/v2/"+mirror.Prefix)
+		}
+
+		files[path.Join("certs.d", mirror.Upstream, "hosts.toml")] = buf.Bytes()
+	}
+	return files
+}
+
+// RegistriesConf 生成 CRI-O 和 podman 使用的 registries.conf（v2 格式），镜像地址可以带路径前缀
+func RegistriesConf(mirrors []Mirror) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# 由 sync-image mirrors 生成\n")
+	for _, mirror := range mirrors {
+		fmt.Fprintf(&buf, "\n[[registry]]\nprefix = %q\nlocation = %q\n\n[[registry.mirror]]\nlocation = %q\n",
+			mirror.Upstream, mirror.Upstream, mirror.Location())
+	}
+	return buf.Bytes()
+}
+
+// DockerDaemon 将 registry-mirrors 合并到已有的 daemon.json 内容中，existing 为空时生成新的配置
+// Docker 只支持 Docker Hub 的镜像加速，且加速地址不能带路径，其他映射会生成警告
+func DockerDaemon(mirrors []Mirror, existing []byte) ([]byte, []string, error) {
+	var warnings []string
+	var locations []string
+	for _, mirror := range mirrors {
+		switch {
+		case mirror.Upstream != utils.DefaultDomain:
+			warnings = append(warnings, fmt.Sprintf("%s: Docker 只支持 Docker Hub 的镜像加速，已跳过", mirror.Upstream))
+		case mirror.Prefix != "":
+			warnings = append(warnings, fmt.Sprintf("%s: Docker 的镜像加速地址不能带路径前缀 %s，已跳过", mirror.Upstream, mirror.Prefix))
+		default:
+			locations = append(locations, "https://"+mirror.Registry)
+		}
+	}
+
+	daemon := make(map[string]interface{})
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := json.Unmarshal(existing, &daemon); err != nil {
+			return nil, warnings, fmt.Errorf("解析 daemon.json 失败: %w", err)
+		}
+	}
+	if len(locations) == 0 {
+		warnings = append(warnings, "没有可以用于 Docker 的镜像加速地址，未修改 registry-mirrors")
+	} else {
+		daemon["registry-mirrors"] = locations
+	}
+
+	data, err := json.MarshalIndent(daemon, "", "  ")
+	if err != nil {
+		return nil, warnings, err
+	}
+	return append(data, '\n'), warnings, nil
+}
@@ -0,0 +1,128 @@
+// Package puller 在客户端使用本地容器运行时拉取已同步的镜像，并重新打上上游镜像名称
+package puller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"sync-image/pkg/logger"
+	"sync-image/pkg/utils"
+)
+
+// 支持的容器运行时
+const (
+	RuntimeAuto    = "auto"
+	RuntimeDocker  = "docker"
+	RuntimeNerdctl = "nerdctl"
+	RuntimePodman  = "podman"
+	RuntimeCtr     = "ctr"
+	RuntimeCrictl  = "crictl"
+)
+
+// DefaultNamespace kubelet 使用的 containerd 命名空间
+const DefaultNamespace = "k8s.io"
+
+// detectOrder 自动检测运行时的顺序
+var detectOrder = []string{RuntimeDocker, RuntimeNerdctl, RuntimePodman, RuntimeCtr, RuntimeCrictl}
+
+// Runtimes 返回支持的运行时名称，包括 auto
+func Runtimes() []string {
+	return append([]string{RuntimeAuto}, detectOrder...)
+}
+
+// Command 待执行的命令
+type Command struct {
+	Name string
+	Args []string
+}
+
+// String 返回命令行形式
+func (c Command) String() string {
+	return c.Name + " " + strings.Join(c.Args, " ")
+}
+
+// Detect 返回要使用的运行时
+// runtime 为 auto 或空时按 docker、nerdctl、podman、ctr、crictl 的顺序选择第一个可用的运行时
+func Detect(runtime string) (string, error) {
+	if runtime == "" || runtime == RuntimeAuto {
+		for _, name := range detectOrder {
+			if _, err := exec.LookPath(name); err == nil {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("未找到可用的容器运行时: %s", strings.Join(detectOrder, ", "))
+	}
+
+	if _, err := exec.LookPath(runtime); err != nil {
+		return "", fmt.Errorf("容器运行时 %s 不可用: %w", runtime, err)
+	}
+	return runtime, nil
+}
+
+// Commands 返回使用指定运行时拉取镜像并重新打标签的命令
+// mirrorImage 和 upstreamImage 应为完整的镜像引用，namespace 只对 containerd 运行时生效
+func Commands(runtime, namespace, mirrorImage, upstreamImage string) ([]Command, error) {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	switch runtime {
+	case RuntimeDocker, RuntimePodman:
+		return []Command{
+			{Name: runtime, Args: []string{"pull", mirrorImage}},
+			{Name: runtime, Args: []string{"tag", mirrorImage, upstreamImage}},
+		}, nil
+	case RuntimeNerdctl:
+		return []Command{
+			{Name: runtime, Args: []string{"--namespace", namespace, "pull", mirrorImage}},
+			{Name: runtime, Args: []string{"--namespace", namespace, "tag", mirrorImage, upstreamImage}},
+		}, nil
+	case RuntimeCtr:
+		return []Command{
+			{Name: runtime, Args: []string{"-n", namespace, "image", "pull", mirrorImage}},
+			{Name: runtime, Args: []string{"-n", namespace, "image", "tag", "--force", mirrorImage, upstreamImage}},
+		}, nil
+	case RuntimeCrictl:
+		// crictl 不支持打标签，拉取到 containerd 后使用 ctr 在同一命名空间中打标签
+		if _, err := exec.LookPath(RuntimeCtr); err != nil {
+			return nil, fmt.Errorf("crictl 不支持重新打标签，需要 ctr: %w", err)
+		}
+		return []Command{
+			{Name: runtime, Args: []string{"pull", mirrorImage}},
+			{Name: RuntimeCtr, Args: []string{"-n", namespace, "image", "tag", "--force", mirrorImage, upstreamImage}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的容器运行时: %s", runtime)
+	}
+}
+
+// FullyQualified 返回带域名和标签的镜像引用，去掉摘要，没有标签时使用 latest
+// containerd 和 podman 不会像 docker 一样补全短名称，因此统一使用完整引用
+func FullyQualified(image string) (string, error) {
+	ref, err := utils.ParseNormalizedReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Tag == "" {
+		ref.Tag = "latest"
+	}
+	return ref.NameWithTag(), nil
+}
+
+// Run 依次执行命令，命令输出写入 out
+func Run(ctx context.Context, commands []Command, out io.Writer, log logger.Logger) error {
+	for _, command := range commands {
+		log.Info("执行命令: `%s`", command)
+
+		cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("命令执行失败: %w\n命令: `%s`", err, command)
+		}
+	}
+	return nil
+}
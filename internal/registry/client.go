@@ -0,0 +1,614 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"sync-image/pkg/logger"
+	"sync-image/pkg/utils"
+)
+
+// ErrNotFound 请求的清单或 blob 不存在
+var ErrNotFound = fmt.Errorf("not found in registry")
+
+// dockerHubHost Docker Hub 在镜像引用中的域名
+const dockerHubHost = utils.DefaultDomain
+
+// ImageReference 镜像在仓库 API 中的坐标
+type ImageReference struct {
+	Host       string // 仓库域名，如 docker.io、registry.k8s.io、localhost:5000
+	Repository string // 仓库路径，如 library/nginx
+	Reference  string // 标签或摘要
+}
+
+// String 返回完整的镜像引用
+func (r ImageReference) String() string {
+	name := r.Host + "/" + r.Repository
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		return name + "@" + r.Reference
+	}
+	return name + ":" + r.Reference
+}
+
+// ParseImageReference 解析镜像引用，缺省域名为 docker.io，缺省标签为 latest
+func ParseImageReference(image string) (ImageReference, error) {
+	ref, err := utils.ParseNormalizedReference(image)
+	if err != nil {
+		return ImageReference{}, err
+	}
+
+	return ImageReference{
+		Host:       ref.Domain,
+		Repository: ref.Path,
+		Reference:  ref.TagOrDigest(),
+	}, nil
+}
+
+// Credentials 仓库认证信息
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client 镜像仓库 HTTP API（OCI Distribution）客户端
+// 支持匿名访问、Basic 认证和 Bearer Token 认证
+type Client struct {
+	httpClient  *http.Client
+	credentials map[string]Credentials
+	tokens      map[string]string
+	mu          sync.Mutex
+	logger      logger.Logger
+}
+
+// NewClient 创建新的仓库 API 客户端
+func NewClient(log logger.Logger) *Client {
+	return &Client{
+		httpClient:  &http.Client{Timeout: 30 * time.Minute},
+		credentials: make(map[string]Credentials),
+		tokens:      make(map[string]string),
+		logger:      log,
+	}
+}
+
+// SetCredentials 设置指定仓库域名的认证信息
+func (c *Client) SetCredentials(host string, creds Credentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.credentials[normalizeHost(host)] = creds
+}
+
+// ManifestResponse 清单请求结果
+type ManifestResponse struct {
+	Data      []byte
+	MediaType string
+	Digest    string
+}
+
+// GetManifest 获取清单内容
+func (c *Client) GetManifest(ctx context.Context, ref ImageReference) (*ManifestResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, ref, "/manifests/"+ref.Reference, "pull", manifestHeaders(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", ref, err)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = Digest(data)
+	}
+
+	return &ManifestResponse{
+		Data:      data,
+		MediaType: contentType(resp),
+		Digest:    digest,
+	}, nil
+}
+
+// HeadManifest 获取清单描述符而不下载内容，清单不存在时返回 ErrNotFound
+func (c *Client) HeadManifest(ctx context.Context, ref ImageReference) (*Descriptor, error) {
+	resp, err := c.do(ctx, http.MethodHead, ref, "/manifests/"+ref.Reference, "pull", manifestHeaders(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return &Descriptor{
+		MediaType: contentType(resp),
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Size:      resp.ContentLength,
+	}, nil
+}
+
+// PutManifest 推送清单，reference 为标签或摘要
+func (c *Client) PutManifest(ctx context.Context, ref ImageReference, mediaType string, data []byte) error {
+	headers := http.Header{}
+	headers.Set("Content-Type", mediaType)
+
+	resp, err := c.do(ctx, http.MethodPut, ref, "/manifests/"+ref.Reference, "pull,push", headers, func() io.Reader {
+		return bytes.NewReader(data)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Tag 为已存在的清单添加新标签，清单内容保持不变
+func (c *Client) Tag(ctx context.Context, ref ImageReference, tag string) error {
+	manifest, err := c.GetManifest(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest %s: %w", ref, err)
+	}
+
+	tagged := ImageReference{Host: ref.Host, Repository: ref.Repository, Reference: tag}
+	if err := c.PutManifest(ctx, tagged, manifest.MediaType, manifest.Data); err != nil {
+		return fmt.Errorf("failed to tag %s as %s: %w", ref, tagged, err)
+	}
+	return nil
+}
+
+// BlobExists 检查 blob 是否已存在于仓库中
+func (c *Client) BlobExists(ctx context.Context, ref ImageReference, digest string) (bool, error) {
+	resp, err := c.do(ctx, http.MethodHead, ref, "/blobs/"+digest, "pull", nil, nil)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// GetBlob 下载 blob，调用方负责关闭返回的 ReadCloser
+// 返回的内容在读取结束时会校验摘要
+func (c *Client) GetBlob(ctx context.Context, ref ImageReference, digest string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, ref, "/blobs/"+digest, "pull", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newVerifyingReader(resp.Body, digest), nil
+}
+
+// PushBlob 以单次上传方式推送 blob
+func (c *Client) PushBlob(ctx context.Context, ref ImageReference, digest string, size int64, content io.Reader) error {
+	resp, err := c.do(ctx, http.MethodPost, ref, "/blobs/uploads/", "pull,push", nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start blob upload: %w", err)
+	}
+	resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return fmt.Errorf("registry did not return upload location for %s", ref.Repository)
+	}
+
+	uploadURL, err := c.resolveLocation(ref, location)
+	if err != nil {
+		return err
+	}
+	query := uploadURL.Query()
+	query.Set("digest", digest)
+	uploadURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL.String(), content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	c.authorize(req, ref.Host, scopeFor(ref, "pull,push"))
+
+	putResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload blob %s: %w", digest, err)
+	}
+	defer putResp.Body.Close()
+
+	if putResp.StatusCode != http.StatusCreated {
+		return responseError(putResp, "upload blob "+digest)
+	}
+	return nil
+}
+
+// MountBlob 从同一仓库域名下的 from 仓库挂载 blob，无需重新上传内容
+// 仓库不支持跨仓库挂载或 from 中不存在该 blob 时返回 false
+func (c *Client) MountBlob(ctx context.Context, ref ImageReference, digest, from string) (bool, error) {
+	query := url.Values{}
+	query.Set("mount", digest)
+	query.Set("from", from)
+	scope := scopeFor(ref, "pull,push") + " " + scopeFor(ImageReference{Host: ref.Host, Repository: from}, "pull")
+
+	resp, err := c.doScope(ctx, http.MethodPost, ref, "/blobs/uploads/?"+query.Encode(), scope, nil, nil)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mount blob %s from %s: %w", digest, from, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated {
+		return true, nil
+	}
+
+	// 未能挂载时仓库会开始普通上传，取消该上传会话
+	if location := resp.Header.Get("Location"); location != "" {
+		if uploadURL, err := c.resolveLocation(ref, location); err == nil {
+			if req, err := http.NewRequestWithContext(ctx, http.MethodDelete, uploadURL.String(), nil); err == nil {
+				c.authorize(req, ref.Host, scope)
+				if resp, err := c.httpClient.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// ListTags 列出仓库的所有标签
+func (c *Client) ListTags(ctx context.Context, ref ImageReference) ([]string, error) {
+	var tags []string
+	path := "/tags/list"
+
+	for path != "" {
+		resp, err := c.do(ctx, http.MethodGet, ref, path, "pull", nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tag list: %w", err)
+		}
+		tags = append(tags, page.Tags...)
+
+		path = nextPagePath(resp.Header.Get("Link"), ref.Repository)
+	}
+
+	return tags, nil
+}
+
+// do 执行仓库 API 请求，按需完成认证质询
+func (c *Client) do(ctx context.Context, method string, ref ImageReference, path, actions string, headers http.Header, body func() io.Reader) (*http.Response, error) {
+	return c.doScope(ctx, method, ref, path, scopeFor(ref, actions), headers, body)
+}
+
+// doScope 以指定的权限范围执行仓库 API 请求，scope 可以包含空格分隔的多个范围
+func (c *Client) doScope(ctx context.Context, method string, ref ImageReference, path, scope string, headers http.Header, body func() io.Reader) (*http.Response, error) {
+	endpoint := registryEndpoint(ref.Host) + "/v2/" + ref.Repository + path
+
+	for attempt := 0; attempt < 2; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = body()
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range headers {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		c.authorize(req, ref.Host, scope)
+
+		c.logger.Debug("Registry request: %s %s", method, endpoint)
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("registry request %s %s failed: %w", method, endpoint, err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := c.authenticate(ctx, ref.Host, scope, challenge); err != nil {
+				return nil, err
+			}
+			continue
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return nil, ErrNotFound
+		case resp.StatusCode >= 300:
+			defer resp.Body.Close()
+			return nil, responseError(resp, method+" "+endpoint)
+		}
+
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("registry request %s %s unauthorized", method, endpoint)
+}
+
+// authorize 为请求附加已缓存的认证信息
+func (c *Client) authorize(req *http.Request, host, scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	host = normalizeHost(host)
+	if token, ok := c.tokens[host+"|"+scope]; ok {
+		if strings.HasPrefix(token, "Basic ") {
+			req.Header.Set("Authorization", token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// authenticate 根据 WWW-Authenticate 质询获取认证令牌
+func (c *Client) authenticate(ctx context.Context, host, scope, challenge string) error {
+	host = normalizeHost(host)
+
+	c.mu.Lock()
+	creds, hasCreds := c.credentials[host]
+	c.mu.Unlock()
+
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if !hasCreds {
+			return fmt.Errorf("registry %s requires basic authentication but no credentials configured", host)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(creds.Username, creds.Password)
+		c.storeToken(host, scope, req.Header.Get("Authorization"))
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication challenge from %s: %q", host, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid token realm from %s: %q", host, params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query["scope"] = strings.Fields(scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if hasCreds && creds.Username != "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch registry token from %s: %w", realm.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, "fetch registry token")
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return fmt.Errorf("registry %s returned empty token", host)
+	}
+
+	c.storeToken(host, scope, token.Token)
+	return nil
+}
+
+// storeToken 缓存认证令牌
+func (c *Client) storeToken(host, scope, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokens[host+"|"+scope] = token
+}
+
+// resolveLocation 解析上传地址（可能为相对路径）
+func (c *Client) resolveLocation(ref ImageReference, location string) (*url.URL, error) {
+	base, err := url.Parse(registryEndpoint(ref.Host) + "/")
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload location %q: %w", location, err)
+	}
+	return base.ResolveReference(target), nil
+}
+
+// Digest 计算内容的 sha256 摘要
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// registryEndpoint 返回仓库 API 的基础地址
+func registryEndpoint(host string) string {
+	host = normalizeHost(host)
+	if host == dockerHubHost {
+		host = "registry-1.docker.io"
+	}
+	if strings.HasPrefix(host, "localhost") || strings.HasPrefix(host, "127.0.0.1") {
+		return "http://" + host
+	}
+	return "https://" + host
+}
+
+// normalizeHost 统一仓库域名格式
+func normalizeHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host = strings.TrimSuffix(host, "/")
+	if host == "index.docker.io" || host == "registry-1.docker.io" || host == "" {
+		return dockerHubHost
+	}
+	return host
+}
+
+// scopeFor 生成仓库访问范围
+func scopeFor(ref ImageReference, actions string) string {
+	return "repository:" + ref.Repository + ":" + actions
+}
+
+// manifestHeaders 返回拉取清单所需的请求头
+func manifestHeaders() http.Header {
+	headers := http.Header{}
+	headers.Set("Accept", manifestAcceptHeader)
+	return headers
+}
+
+// contentType 返回响应的媒体类型（去除参数部分）
+func contentType(resp *http.Response) string {
+	mediaType := resp.Header.Get("Content-Type")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	return strings.TrimSpace(mediaType)
+}
+
+// parseChallenge 解析 WWW-Authenticate 头
+func parseChallenge(header string) (scheme string, params map[string]string) {
+	params = make(map[string]string)
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", params
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	scheme = strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return scheme, params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+
+	return scheme, params
+}
+
+// nextPagePath 从 Link 头中解析下一页路径
+func nextPagePath(link, repository string) string {
+	if link == "" {
+		return ""
+	}
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start < 0 || end <= start {
+		return ""
+	}
+
+	next, err := url.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	prefix := "/v2/" + repository
+	if !strings.HasPrefix(next.Path, prefix) {
+		return ""
+	}
+
+	path := strings.TrimPrefix(next.Path, prefix)
+	if next.RawQuery != "" {
+		path += "?" + next.RawQuery
+	}
+	return path
+}
+
+// responseError 将失败响应转换为错误
+func responseError(resp *http.Response, operation string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = resp.Status
+	}
+	return fmt.Errorf("%s failed with status %d: %s", operation, resp.StatusCode, message)
+}
+
+// verifyingReader 读取时计算摘要并在结束时校验
+type verifyingReader struct {
+	reader   io.ReadCloser
+	hasher   hash.Hash
+	expected string
+}
+
+// newVerifyingReader 创建校验摘要的 Reader
+func newVerifyingReader(reader io.ReadCloser, digest string) io.ReadCloser {
+	return &verifyingReader{
+		reader:   reader,
+		hasher:   sha256.New(),
+		expected: digest,
+	}
+}
+
+// Read 实现 io.Reader 接口
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hasher.Write(p[:n])
+	if err == io.EOF && strings.HasPrefix(r.expected, "sha256:") {
+		actual := "sha256:" + hex.EncodeToString(r.hasher.Sum(nil))
+		if actual != r.expected {
+			return n, fmt.Errorf("digest mismatch: expected %s, got %s", r.expected, actual)
+		}
+	}
+	return n, err
+}
+
+// Close 实现 io.Closer 接口
+func (r *verifyingReader) Close() error {
+	return r.reader.Close()
+}
@@ -31,17 +31,17 @@ func (f *RegistryManagerFactory) CreateProcessor(registryURL string) (RegistryPr
 	f.logger.Info("检测到仓库类型: %s -> %s", registryURL, registryType)
 
 	// 所有仓库都使用通用处理器，华为云特殊处理集成在通用处理器中
-	return f.createGenericProcessor(), nil
+	return f.createGenericProcessor(registryURL), nil
 }
 
 // createHuaweiSWRProcessor function removed - now integrated into generic processor
 
 // createGenericProcessor 创建通用处理器（集成后处理机制）
-func (f *RegistryManagerFactory) createGenericProcessor() RegistryProcessor {
+func (f *RegistryManagerFactory) createGenericProcessor(registryURL string) RegistryProcessor {
 	f.logger.Debug("创建通用处理器")
 
-	// 获取有效的通用仓库配置
-	genericConfig := f.config.GetEffectiveGenericConfig()
+	// 获取仓库地址对应的目标仓库配置（规则路由的目标仓库或通用仓库配置）
+	genericConfig := f.config.GetDestinationForRegistry(registryURL)
 
 	if genericConfig != nil {
 		// 创建后处理器管理器
@@ -55,6 +55,34 @@ func (f *RegistryManagerFactory) createGenericProcessor() RegistryProcessor {
 	return NewGenericProcessor(f.logger)
 }
 
+// DetectRegistryType 检测仓库类型
+func (f *RegistryManagerFactory) DetectRegistryType(registryURL string) RegistryType {
+	return f.detector.DetectRegistryType(registryURL)
+}
+
+// PlannedPostProcessors 返回推送到指定仓库后将会执行的后处理器名称（不执行处理）
+func (f *RegistryManagerFactory) PlannedPostProcessors(imageName, registryURL string) []string {
+	if f.config.GetDestinationForRegistry(registryURL) == nil {
+		return nil
+	}
+
+	manager := NewPostProcessorFactory(f.config, f.logger).CreateManager()
+	var names []string
+	for _, processor := range manager.ApplicableProcessors(imageName, registryURL) {
+		names = append(names, processor.GetName())
+	}
+	return names
+}
+
+// ExpectsPublicAccess 检查推送到指定仓库的镜像在后处理后是否应当可以匿名访问
+func (f *RegistryManagerFactory) ExpectsPublicAccess(imageName, registryURL string) bool {
+	if f.config.GetDestinationForRegistry(registryURL) == nil {
+		return false
+	}
+	manager := NewPostProcessorFactory(f.config, f.logger).CreateManager()
+	return manager.ExpectsPublicAccess(imageName, registryURL)
+}
+
 // GetSupportedRegistryTypes 获取支持的仓库类型列表
 func (f *RegistryManagerFactory) GetSupportedRegistryTypes() []RegistryType {
 	return []RegistryType{
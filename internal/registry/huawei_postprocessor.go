@@ -6,6 +6,7 @@ import (
 
 	"sync-image/internal/config"
 	"sync-image/pkg/logger"
+	"sync-image/pkg/utils"
 )
 
 // HuaweiSWRPostProcessor 华为云SWR后处理器
@@ -74,28 +75,20 @@ func (p *HuaweiSWRPostProcessor) Process(imageName, registryURL string) error {
 }
 
 // parseImageName 解析华为云SWR镜像名称
+// 支持 registry/namespace/repository:tag 和 namespace/repository:tag 两种格式
 func (p *HuaweiSWRPostProcessor) parseImageName(imageName string) (namespace, repository string, err error) {
-	// 移除仓库前缀（如果存在）
-	parts := strings.Split(imageName, "/")
-	if len(parts) < 2 {
-		return "", "", fmt.Errorf("invalid Huawei SWR image name format: %s", imageName)
-	}
-
-	// 华为云SWR格式：registry/namespace/repository:tag
-	if len(parts) >= 3 && strings.Contains(parts[0], "myhuaweicloud.com") {
-		namespace = parts[1]
-		repository = strings.Split(parts[2], ":")[0] // 移除标签
-	} else if len(parts) >= 2 {
-		// 回退格式：namespace/repository:tag
-		namespace = parts[0]
-		repository = strings.Split(parts[1], ":")[0] // 移除标签
+	ref, err := utils.ParseReference(imageName)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Huawei SWR image name format: %s: %w", imageName, err)
 	}
 
-	if namespace == "" || repository == "" {
+	// 华为云SWR格式：命名空间为路径第一段，其余部分为仓库名
+	parts := strings.SplitN(ref.Path, "/", 2)
+	if len(parts) < 2 {
 		return "", "", fmt.Errorf("failed to extract namespace and repository from: %s", imageName)
 	}
 
-	return namespace, repository, nil
+	return parts[0], parts[1], nil
 }
 
 // setImagePublic 设置华为云SWR镜像为公开访问
@@ -154,6 +147,11 @@ func (p *HuaweiSWRPostProcessor) maskSensitive(s string) string {
 	return s[:4] + "****" + s[len(s)-4:]
 }
 
+// SetsPublicAccess 华为云SWR后处理器会将镜像设置为公开访问
+func (p *HuaweiSWRPostProcessor) SetsPublicAccess() bool {
+	return true
+}
+
 // GetSupportedRegistries 获取支持的仓库模式
 func (p *HuaweiSWRPostProcessor) GetSupportedRegistries() []string {
 	return []string{
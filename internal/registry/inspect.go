@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ImageInspection 镜像清单解析结果
+type ImageInspection struct {
+	Reference          ImageReference
+	Digest             string             // 顶层清单摘要
+	MediaType          string             // 顶层清单媒体类型
+	AvailablePlatforms []string           // 上游镜像提供的全部平台
+	Images             []PlatformManifest // 匹配请求平台的单架构清单
+}
+
+// PlatformManifest 单个平台的清单
+type PlatformManifest struct {
+	Platform string
+	Digest   string
+	Blobs    []Descriptor // config 和 layers
+}
+
+// Size 返回该平台所有 blob 的总大小
+func (m PlatformManifest) Size() int64 {
+	var size int64
+	for _, blob := range m.Blobs {
+		size += blob.Size
+	}
+	return size
+}
+
+// Inspect 解析镜像的平台清单，platforms 不为空时只解析匹配的平台
+func (c *Client) Inspect(ctx context.Context, ref ImageReference, platforms []string) (*ImageInspection, error) {
+	resp, err := c.GetManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := ParseManifest(resp.Data, resp.MediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &ImageInspection{
+		Reference: ref,
+		Digest:    resp.Digest,
+		MediaType: manifest.MediaType,
+	}
+
+	if !manifest.IsIndex() {
+		platform, err := c.configPlatform(ctx, ref, manifest)
+		if err != nil {
+			return nil, err
+		}
+		inspection.AvailablePlatforms = []string{platform}
+		if len(platforms) == 0 || platformMatches(platform, platforms) {
+			inspection.Images = append(inspection.Images, PlatformManifest{
+				Platform: platform,
+				Digest:   resp.Digest,
+				Blobs:    manifest.BlobDescriptors(),
+			})
+		}
+		return inspection, nil
+	}
+
+	for _, child := range manifest.Manifests {
+		// 跳过构建证明等非镜像清单
+		if child.Platform == nil || child.Platform.OS == "unknown" {
+			continue
+		}
+		inspection.AvailablePlatforms = append(inspection.AvailablePlatforms, child.Platform.String())
+		if len(platforms) > 0 && !child.Platform.Matches(platforms) {
+			continue
+		}
+
+		childRef := ref
+		childRef.Reference = child.Digest
+		childResp, err := c.GetManifest(ctx, childRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch manifest %s: %w", child.Digest, err)
+		}
+		childManifest, err := ParseManifest(childResp.Data, child.MediaType)
+		if err != nil {
+			return nil, err
+		}
+
+		inspection.Images = append(inspection.Images, PlatformManifest{
+			Platform: child.Platform.String(),
+			Digest:   child.Digest,
+			Blobs:    childManifest.BlobDescriptors(),
+		})
+	}
+
+	return inspection, nil
+}
+
+// configPlatform 从单架构镜像的 config 中读取平台
+func (c *Client) configPlatform(ctx context.Context, ref ImageReference, manifest *Manifest) (string, error) {
+	if manifest.Config == nil {
+		return "", fmt.Errorf("manifest has no config")
+	}
+
+	reader, err := c.GetBlob(ctx, ref, manifest.Config.Digest)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch config %s: %w", manifest.Config.Digest, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config %s: %w", manifest.Config.Digest, err)
+	}
+
+	var config imageConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse config %s: %w", manifest.Config.Digest, err)
+	}
+
+	platform := &Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}
+	return platform.String(), nil
+}
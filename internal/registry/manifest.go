@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 镜像清单媒体类型
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// manifestAcceptHeader 拉取清单时声明接受的媒体类型
+var manifestAcceptHeader = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+// Platform 镜像平台信息
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// String 返回 os/arch[/variant] 格式的平台字符串
+func (p *Platform) String() string {
+	if p == nil {
+		return ""
+	}
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+// Matches 检查平台是否匹配请求的平台列表（未指定 variant 时忽略 variant）
+func (p *Platform) Matches(platforms []string) bool {
+	if p == nil {
+		return false
+	}
+	for _, requested := range platforms {
+		requested = strings.TrimSpace(requested)
+		if requested == p.String() || requested == p.OS+"/"+p.Architecture {
+			return true
+		}
+	}
+	return false
+}
+
+// Descriptor 内容描述符
+type Descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *Platform `json:"platform,omitempty"`
+}
+
+// Manifest 镜像清单或清单索引
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        *Descriptor  `json:"config,omitempty"`
+	Layers        []Descriptor `json:"layers,omitempty"`
+	Manifests     []Descriptor `json:"manifests,omitempty"`
+}
+
+// IsIndex 检查是否为多架构清单索引
+func (m *Manifest) IsIndex() bool {
+	return IsIndexMediaType(m.MediaType) || len(m.Manifests) > 0
+}
+
+// BlobDescriptors 返回单架构清单引用的所有 blob（config 和 layers）
+func (m *Manifest) BlobDescriptors() []Descriptor {
+	var blobs []Descriptor
+	if m.Config != nil {
+		blobs = append(blobs, *m.Config)
+	}
+	return append(blobs, m.Layers...)
+}
+
+// IsIndexMediaType 检查媒体类型是否为清单索引
+func IsIndexMediaType(mediaType string) bool {
+	return mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerManifestList
+}
+
+// ParseManifest 解析清单内容，mediaType 为空时使用清单内声明的类型
+func ParseManifest(data []byte, mediaType string) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = mediaType
+	}
+	if manifest.MediaType == "" {
+		if len(manifest.Manifests) > 0 {
+			manifest.MediaType = MediaTypeOCIIndex
+		} else {
+			manifest.MediaType = MediaTypeOCIManifest
+		}
+	}
+	return &manifest, nil
+}
+
+// FilterIndex 只保留匹配平台的子清单，返回重写后的索引内容
+// 如果所有子清单都匹配，返回原始内容以保持摘要不变
+func FilterIndex(data []byte, platforms []string) ([]byte, []Descriptor, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw["manifests"], &entries); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse index manifests: %w", err)
+	}
+
+	var (
+		all      []Descriptor
+		selected []Descriptor
+		kept     []json.RawMessage
+	)
+	for _, entry := range entries {
+		var descriptor Descriptor
+		if err := json.Unmarshal(entry, &descriptor); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse index manifests: %w", err)
+		}
+		all = append(all, descriptor)
+		if descriptor.Platform.Matches(platforms) {
+			selected = append(selected, descriptor)
+			kept = append(kept, entry)
+		}
+	}
+
+	if len(selected) == len(all) {
+		return data, all, nil
+	}
+	if len(selected) == 0 {
+		return nil, nil, fmt.Errorf("no manifest in index matches platforms %v", platforms)
+	}
+
+	encoded, err := json.Marshal(kept)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw["manifests"] = encoded
+
+	rewritten, err := json.Marshal(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rewritten, selected, nil
+}
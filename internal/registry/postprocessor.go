@@ -24,6 +24,15 @@ type PostProcessor interface {
 	GetDescription() string
 }
 
+// PublicAccessPostProcessor 会将镜像设置为公开访问的后处理器
+// 推送后校验会据此决定是否需要匿名拉取目标镜像
+type PublicAccessPostProcessor interface {
+	PostProcessor
+
+	// SetsPublicAccess 是否会将镜像设置为公开访问
+	SetsPublicAccess() bool
+}
+
 // PostProcessorManager 后处理器管理器
 type PostProcessorManager struct {
 	processors []PostProcessor
@@ -79,6 +88,28 @@ func (m *PostProcessorManager) ProcessImage(imageName, registryURL string) error
 	return nil
 }
 
+// ApplicableProcessors 返回会对指定镜像执行的后处理器（不执行处理）
+func (m *PostProcessorManager) ApplicableProcessors(imageName, registryURL string) []PostProcessor {
+	var applicable []PostProcessor
+	for _, processor := range m.processors {
+		if processor.CanProcess(imageName, registryURL) {
+			applicable = append(applicable, processor)
+		}
+	}
+	return applicable
+}
+
+// ExpectsPublicAccess 检查后处理完成后镜像是否应当可以匿名访问
+func (m *PostProcessorManager) ExpectsPublicAccess(imageName, registryURL string) bool {
+	for _, processor := range m.processors {
+		publicProcessor, ok := processor.(PublicAccessPostProcessor)
+		if ok && publicProcessor.SetsPublicAccess() && processor.CanProcess(imageName, registryURL) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetRegisteredProcessors 获取已注册的后处理器列表
 func (m *PostProcessorManager) GetRegisteredProcessors() []PostProcessor {
 	return m.processors
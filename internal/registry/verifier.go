@@ -0,0 +1,354 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"sync-image/pkg/logger"
+)
+
+// verifyAttempts 目标清单暂不可见时的重试次数（部分仓库存在短暂的最终一致性延迟）
+const verifyAttempts = 3
+
+// verifyRetryDelay 重试间隔
+var verifyRetryDelay = 5 * time.Second
+
+// VerifyOptions 推送后校验选项
+type VerifyOptions struct {
+	Platforms     []string // 请求同步的平台
+	Anonymous     bool     // 是否同时匿名拉取目标清单以确认公开可见
+	CompareConfig bool     // 是否比较 config 摘要；buildx 重建索引时会生成新的 config，默认不比较
+}
+
+// VerificationError 目标镜像与上游镜像不一致
+type VerificationError struct {
+	Source string
+	Target string
+	Diffs  []string
+}
+
+// Error 实现 error 接口
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("target %s does not match upstream %s (%d differences)", e.Target, e.Source, len(e.Diffs))
+}
+
+// Diff 返回逐行的差异描述
+func (e *VerificationError) Diff() string {
+	return strings.Join(e.Diffs, "\n")
+}
+
+// platformImage 单个平台镜像的内容摘要
+type platformImage struct {
+	Config  string
+	Layers  []string
+	DiffIDs []string
+}
+
+// imageConfig 镜像 config 中校验所需的字段
+type imageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant"`
+	RootFS       struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// Verifier 推送后校验器
+// 比较目标镜像和上游镜像的平台集合和 layer 摘要（可选比较 config 摘要）
+type Verifier struct {
+	client    *Client
+	anonymous *Client
+	logger    logger.Logger
+}
+
+// NewVerifier 创建新的校验器，client 用于带认证访问目标仓库
+func NewVerifier(client *Client, log logger.Logger) *Verifier {
+	return &Verifier{
+		client:    client,
+		anonymous: NewClient(log),
+		logger:    log,
+	}
+}
+
+// Verify 校验目标镜像，不一致时返回 *VerificationError
+func (v *Verifier) Verify(ctx context.Context, source, target string, opts VerifyOptions) error {
+	v.logger.Info("Verifying pushed image: %s against upstream %s", target, source)
+
+	sourceRef, err := ParseImageReference(source)
+	if err != nil {
+		return err
+	}
+	targetRef, err := ParseImageReference(target)
+	if err != nil {
+		return err
+	}
+
+	upstream, err := v.resolve(ctx, v.client, sourceRef, opts.Platforms)
+	if err != nil {
+		return fmt.Errorf("failed to resolve upstream image %s: %w", source, err)
+	}
+
+	verification := &VerificationError{Source: source, Target: target}
+
+	var pushed map[string]*platformImage
+	for attempt := 1; ; attempt++ {
+		pushed, err = v.resolve(ctx, v.client, targetRef, nil)
+		if err != ErrNotFound || attempt >= verifyAttempts {
+			break
+		}
+		v.logger.Debug("Target manifest not visible yet, retrying in %s", verifyRetryDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(verifyRetryDelay):
+		}
+	}
+	if err == ErrNotFound {
+		verification.Diffs = append(verification.Diffs, "! target manifest not found")
+		return verification
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve target image %s: %w", target, err)
+	}
+
+	if opts.Anonymous {
+		if _, err := v.anonymous.HeadManifest(ctx, targetRef); err != nil {
+			verification.Diffs = append(verification.Diffs, fmt.Sprintf("! anonymous pull failed, image is not public: %v", err))
+		}
+	}
+
+	verification.Diffs = append(verification.Diffs, comparePlatforms(upstream, pushed, opts)...)
+
+	if len(verification.Diffs) > 0 {
+		v.logger.Warn("Verification failed for %s:\n%s", target, verification.Diff())
+		return verification
+	}
+
+	v.logger.Info("Verification passed: %s matches upstream (%d platforms)", target, len(pushed))
+	return nil
+}
+
+// Compare 比较目标镜像和上游镜像的内容，返回逐行的差异描述，内容一致时返回空列表
+// 目标镜像不存在时返回 ErrNotFound；与 Verify 不同，不等待目标清单可见，也不检查匿名拉取
+func (v *Verifier) Compare(ctx context.Context, source, target string, opts VerifyOptions) ([]string, error) {
+	sourceRef, err := ParseImageReference(source)
+	if err != nil {
+		return nil, err
+	}
+	targetRef, err := ParseImageReference(target)
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, err := v.resolve(ctx, v.client, sourceRef, opts.Platforms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upstream image %s: %w", source, err)
+	}
+	matched := false
+	for platform := range upstream {
+		if len(opts.Platforms) == 0 || platformMatches(platform, opts.Platforms) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("upstream image %s has none of the platforms %s", source, strings.Join(opts.Platforms, ","))
+	}
+
+	pushed, err := v.resolve(ctx, v.client, targetRef, nil)
+	if err == ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target image %s: %w", target, err)
+	}
+
+	return comparePlatforms(upstream, pushed, opts), nil
+}
+
+// comparePlatforms 比较上游和目标镜像的平台、config 与 layer
+func comparePlatforms(upstream, pushed map[string]*platformImage, opts VerifyOptions) []string {
+	var diffs []string
+
+	expected := make(map[string]*platformImage)
+	for platform, image := range upstream {
+		if len(opts.Platforms) == 0 || platformMatches(platform, opts.Platforms) {
+			expected[platform] = image
+		}
+	}
+
+	for _, platform := range sortedKeys(expected) {
+		if _, ok := pushed[platform]; !ok {
+			diffs = append(diffs, fmt.Sprintf("- platform %s (missing in target)", platform))
+		}
+	}
+	for _, platform := range sortedKeys(pushed) {
+		if _, ok := expected[platform]; !ok {
+			diffs = append(diffs, fmt.Sprintf("+ platform %s (not expected from upstream)", platform))
+		}
+	}
+
+	for _, platform := range sortedKeys(expected) {
+		want, got := expected[platform], pushed[platform]
+		if got == nil {
+			continue
+		}
+
+		// config 中的 created 等字段在 buildx 重建时会变化，只在明确要求时比较摘要
+		if opts.CompareConfig && want.Config != got.Config {
+			diffs = append(diffs, fmt.Sprintf("~ %s config: upstream %s, target %s", platform, want.Config, got.Config))
+		}
+
+		// 压缩后的 layer 摘要可能因重新压缩而不同，此时以未压缩的 diff_id 为准
+		if equalStrings(want.Layers, got.Layers) {
+			continue
+		}
+		wantLayers, gotLayers := want.Layers, got.Layers
+		if len(want.DiffIDs) > 0 && len(got.DiffIDs) > 0 {
+			wantLayers, gotLayers = want.DiffIDs, got.DiffIDs
+		}
+		diffs = append(diffs, compareLayers(platform, wantLayers, gotLayers)...)
+	}
+
+	return diffs
+}
+
+// compareLayers 逐层比较 layer 摘要
+func compareLayers(platform string, want, got []string) []string {
+	if len(want) != len(got) {
+		return []string{fmt.Sprintf("~ %s layer count: upstream %d, target %d", platform, len(want), len(got))}
+	}
+
+	var diffs []string
+	for i := range want {
+		if want[i] != got[i] {
+			diffs = append(diffs, fmt.Sprintf("~ %s layer[%d]: upstream %s, target %s", platform, i, want[i], got[i]))
+		}
+	}
+	return diffs
+}
+
+// resolve 解析镜像的各平台内容摘要，platforms 不为空时只解析匹配的平台
+func (v *Verifier) resolve(ctx context.Context, client *Client, ref ImageReference, platforms []string) (map[string]*platformImage, error) {
+	resp, err := client.GetManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := ParseManifest(resp.Data, resp.MediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]*platformImage)
+	if !manifest.IsIndex() {
+		platform, image, err := v.resolveImage(ctx, client, ref, manifest)
+		if err != nil {
+			return nil, err
+		}
+		images[platform] = image
+		return images, nil
+	}
+
+	for _, child := range manifest.Manifests {
+		// 跳过构建证明等非镜像清单
+		if child.Platform == nil || child.Platform.OS == "unknown" {
+			continue
+		}
+		if len(platforms) > 0 && !child.Platform.Matches(platforms) {
+			continue
+		}
+
+		childRef := ref
+		childRef.Reference = child.Digest
+		childResp, err := client.GetManifest(ctx, childRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch manifest %s: %w", child.Digest, err)
+		}
+		childManifest, err := ParseManifest(childResp.Data, child.MediaType)
+		if err != nil {
+			return nil, err
+		}
+
+		_, image, err := v.resolveImage(ctx, client, ref, childManifest)
+		if err != nil {
+			return nil, err
+		}
+		images[child.Platform.String()] = image
+	}
+
+	return images, nil
+}
+
+// resolveImage 读取单架构镜像的 config，返回平台和内容摘要
+func (v *Verifier) resolveImage(ctx context.Context, client *Client, ref ImageReference, manifest *Manifest) (string, *platformImage, error) {
+	if manifest.Config == nil {
+		return "", nil, fmt.Errorf("manifest has no config")
+	}
+
+	image := &platformImage{Config: manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		image.Layers = append(image.Layers, layer.Digest)
+	}
+
+	reader, err := client.GetBlob(ctx, ref, manifest.Config.Digest)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch config %s: %w", manifest.Config.Digest, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read config %s: %w", manifest.Config.Digest, err)
+	}
+
+	var config imageConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", nil, fmt.Errorf("failed to parse config %s: %w", manifest.Config.Digest, err)
+	}
+	image.DiffIDs = config.RootFS.DiffIDs
+
+	platform := &Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}
+	return platform.String(), image, nil
+}
+
+// platformMatches 检查 os/arch[/variant] 字符串是否匹配请求的平台
+func platformMatches(platform string, requested []string) bool {
+	parts := strings.SplitN(platform, "/", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	p := &Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p.Matches(requested)
+}
+
+// sortedKeys 返回排序后的平台列表
+func sortedKeys(images map[string]*platformImage) []string {
+	keys := make([]string, 0, len(images))
+	for key := range images {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// equalStrings 比较两个字符串切片
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
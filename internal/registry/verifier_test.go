@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sync-image/pkg/logger"
+)
+
+// fakeRegistry 内存中的只读镜像仓库，按 仓库名 -> 引用 保存清单，blob 所有仓库共享
+type fakeRegistry struct {
+	manifests map[string]map[string][]byte
+	blobs     map[string][]byte
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		manifests: make(map[string]map[string][]byte),
+		blobs:     make(map[string][]byte),
+	}
+}
+
+// ServeHTTP 处理 /v2/<repo>/manifests/<ref> 和 /v2/<repo>/blobs/<digest>
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if i := strings.LastIndex(path, "/manifests/"); i >= 0 {
+		data, ok := r.manifests[path[:i]][path[i+len("/manifests/"):]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		var manifest Manifest
+		json.Unmarshal(data, &manifest)
+		w.Header().Set("Content-Type", manifest.MediaType)
+		w.Header().Set("Docker-Content-Digest", Digest(data))
+		w.Write(data)
+		return
+	}
+	if i := strings.LastIndex(path, "/blobs/"); i >= 0 {
+		data, ok := r.blobs[path[i+len("/blobs/"):]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write(data)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// blob 保存 blob 并返回摘要
+func (r *fakeRegistry) blob(data []byte) string {
+	digest := Digest(data)
+	r.blobs[digest] = data
+	return digest
+}
+
+// manifest 保存清单，同时以标签和摘要作为引用
+func (r *fakeRegistry) manifest(t *testing.T, repo, tag string, manifest Manifest) Descriptor {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := Digest(data)
+	if r.manifests[repo] == nil {
+		r.manifests[repo] = make(map[string][]byte)
+	}
+	r.manifests[repo][digest] = data
+	if tag != "" {
+		r.manifests[repo][tag] = data
+	}
+	return Descriptor{MediaType: manifest.MediaType, Digest: digest, Size: int64(len(data))}
+}
+
+// image 保存单架构镜像，created 不同时生成不同的 config
+func (r *fakeRegistry) image(t *testing.T, repo, arch, created string, diffIDs, layers []string) Descriptor {
+	t.Helper()
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": arch,
+		"os":           "linux",
+		"created":      created,
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": diffIDs},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Config:        &Descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: r.blob(config), Size: int64(len(config))},
+	}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, Descriptor{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: r.blob([]byte(layer))})
+	}
+	return r.manifest(t, repo, "", manifest)
+}
+
+// index 保存多架构清单索引，images 按 amd64、arm64 顺序排列
+func (r *fakeRegistry) index(t *testing.T, repo, tag string, images ...Descriptor) {
+	t.Helper()
+	index := Manifest{SchemaVersion: 2, MediaType: MediaTypeOCIIndex}
+	for i, image := range images {
+		image.Platform = &Platform{OS: "linux", Architecture: []string{"amd64", "arm64"}[i]}
+		index.Manifests = append(index.Manifests, image)
+	}
+	r.manifest(t, repo, tag, index)
+}
+
+func TestVerifierCompareRebuiltIndex(t *testing.T) {
+	diffIDs := map[string][]string{
+		"amd64": {Digest([]byte("amd64 base")), Digest([]byte("amd64 app"))},
+		"arm64": {Digest([]byte("arm64 base")), Digest([]byte("arm64 app"))},
+	}
+
+	reg := newFakeRegistry()
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	reg.index(t, "library/app", "v1",
+		reg.image(t, "library/app", "amd64", "2024-01-01T00:00:00Z", diffIDs["amd64"], []string{"amd64 base.gz", "amd64 app.gz"}),
+		reg.image(t, "library/app", "arm64", "2024-01-01T00:00:00Z", diffIDs["arm64"], []string{"arm64 base.gz", "arm64 app.gz"}),
+	)
+	// buildx 重建索引：config 的 created 改变，layer 被重新压缩，但未压缩内容不变
+	reg.index(t, "mirror/app", "v1",
+		reg.image(t, "mirror/app", "amd64", "2024-06-01T12:00:00Z", diffIDs["amd64"], []string{"amd64 base.zst", "amd64 app.zst"}),
+		reg.image(t, "mirror/app", "arm64", "2024-06-01T12:00:00Z", diffIDs["arm64"], []string{"arm64 base.zst", "arm64 app.zst"}),
+	)
+	// 内容不同的镜像：arm64 的应用层被替换
+	reg.index(t, "mirror/app", "stale",
+		reg.image(t, "mirror/app", "amd64", "2024-06-01T12:00:00Z", diffIDs["amd64"], []string{"amd64 base.zst", "amd64 app.zst"}),
+		reg.image(t, "mirror/app", "arm64", "2024-06-01T12:00:00Z", []string{diffIDs["arm64"][0], Digest([]byte("arm64 old app"))}, []string{"arm64 base.zst", "arm64 old app.zst"}),
+	)
+	// 缺少平台的镜像：只有 amd64
+	reg.index(t, "mirror/app", "amd64",
+		reg.image(t, "mirror/app", "amd64", "2024-06-01T12:00:00Z", diffIDs["amd64"], []string{"amd64 base.zst", "amd64 app.zst"}),
+	)
+
+	verifier := NewVerifier(NewClient(logger.NewLogger("error")), logger.NewLogger("error"))
+	source := host + "/library/app:v1"
+	platforms := []string{"linux/amd64", "linux/arm64"}
+
+	tests := []struct {
+		name      string
+		target    string
+		opts      VerifyOptions
+		wantDiffs []string
+	}{
+		{
+			name:   "rebuilt config is accepted by default",
+			target: host + "/mirror/app:v1",
+			opts:   VerifyOptions{Platforms: platforms},
+		},
+		{
+			name:      "rebuilt config is reported when comparing config",
+			target:    host + "/mirror/app:v1",
+			opts:      VerifyOptions{Platforms: platforms, CompareConfig: true},
+			wantDiffs: []string{"~ linux/amd64 config", "~ linux/arm64 config"},
+		},
+		{
+			name:      "changed layer is reported",
+			target:    host + "/mirror/app:stale",
+			opts:      VerifyOptions{Platforms: platforms},
+			wantDiffs: []string{"~ linux/arm64 layer[1]"},
+		},
+		{
+			name:      "missing platform is reported",
+			target:    host + "/mirror/app:amd64",
+			opts:      VerifyOptions{Platforms: platforms},
+			wantDiffs: []string{"- platform linux/arm64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := verifier.Compare(context.Background(), source, tt.target, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(diffs) != len(tt.wantDiffs) {
+				t.Fatalf("got diffs %q, want prefixes %q", diffs, tt.wantDiffs)
+			}
+			for i, want := range tt.wantDiffs {
+				if !strings.HasPrefix(diffs[i], want) {
+					t.Errorf("diff[%d] = %q, want prefix %q", i, diffs[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"sync-image/internal/docker"
+	githubclient "sync-image/internal/github"
+	"sync-image/pkg/utils"
+)
+
+// imageResult Issue 中单个镜像的同步结果
+type imageResult struct {
+	request     utils.ImageRequest
+	transformed *docker.TransformResult  // 为空表示未能完成镜像名称转换
+	build       *docker.BuildResult      // 为空表示未开始构建
+	digest      string                   // 上游镜像的清单摘要，无法获取时为空
+	duplicate   *githubclient.SyncRecord // 上游未变化、直接复用的历史同步记录
+	previous    *githubclient.SyncRecord // 上游已更新前的历史同步记录
+	bytes       int64                    // 同步的数据量，只在启用每日数据量限制时统计
+	err         error
+}
+
+// generateBatchResult 生成 Issue 内容中镜像列表的结果报告，包含每个镜像的状态表格
+func (s *DefaultSyncService) generateBatchResult(results []*imageResult) string {
+	var b strings.Builder
+
+	failed := 0
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+	}
+	switch {
+	case failed == 0:
+		b.WriteString(fmt.Sprintf("**✅ 转换完成**，共 %d 个镜像\n\n", len(results)))
+	case failed == len(results):
+		b.WriteString(fmt.Sprintf("**❌ 转换失败**，共 %d 个镜像\n\n", len(results)))
+	default:
+		b.WriteString(fmt.Sprintf("**⚠️ 部分转换失败**，共 %d 个镜像，成功 %d 个，失败 %d 个\n\n",
+			len(results), len(results)-failed, failed))
+	}
+
+	b.WriteString("| # | 源镜像 | 目标镜像 | 架构 | 结果 |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for n, result := range results {
+		source, target := result.request.Image, ""
+		if result.transformed != nil {
+			source, target = result.transformed.SourceImage, result.transformed.TargetImage
+		}
+		if target != "" && len(result.request.Tags) > 0 {
+			target = code(target) + "<br>额外标签: " + code(strings.Join(result.request.Tags, ", "))
+		} else {
+			target = code(target)
+		}
+
+		platform := result.request.Platform
+		if platform == "" {
+			platform = s.config.Platforms
+		}
+
+		status := "✅ 成功"
+		switch {
+		case result.err != nil:
+			status = "❌ 失败"
+		case result.duplicate != nil:
+			status = fmt.Sprintf("♻️ 已在 #%d 同步，上游未变化", result.duplicate.Issue)
+		case result.previous != nil:
+			status = fmt.Sprintf("✅ 成功（上游已更新，上次见 #%d）", result.previous.Issue)
+		}
+		b.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s |\n",
+			n+1, orDash(code(source)), orDash(target), code(platform), status))
+	}
+
+	// 成功的镜像给出拉取命令
+	var commands []string
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		source, target := result.transformed.SourceImage, result.transformed.TargetImage
+		pull := "docker pull " + target
+		if result.request.Platform != "" {
+			pull += " --platform " + result.request.Platform
+		}
+		commands = append(commands, pull, "docker tag "+target+" "+source, "")
+	}
+	if len(commands) > 0 {
+		b.WriteString("\n```bash\n# 下载并重命名镜像\n")
+		b.WriteString(strings.Join(commands, "\n"))
+		b.WriteString("```\n")
+	}
+
+	// 重定向和失败详情
+	for n, result := range results {
+		if result.transformed != nil && result.transformed.Redirected() {
+			b.WriteString(fmt.Sprintf("\n> ℹ️ 第 %d 个镜像所在的仓库已停止更新，本次从 `%s` 拉取，目标镜像名称保持不变\n",
+				n+1, result.transformed.UpstreamImage))
+		}
+	}
+	for n, result := range results {
+		if result.err == nil {
+			continue
+		}
+		message, details, diff := s.describeError(result.err)
+		b.WriteString(fmt.Sprintf("\n**第 %d 个镜像失败原因**: %s\n", n+1, message))
+		if diff != "" {
+			b.WriteString("\n```diff\n" + diff + "\n```\n")
+		}
+		b.WriteString("\n<details><summary>详细错误信息</summary>\n\n```\n" + details + "\n```\n\n</details>\n")
+		if result.build != nil && result.build.ArchitectureInfo != "" {
+			b.WriteString("\n" + result.build.ArchitectureInfo + "\n")
+		}
+	}
+
+	for _, result := range results {
+		if result.err == nil {
+			if record := s.syncRecord(result); record != "" {
+				b.WriteString(record + "\n")
+			}
+		}
+	}
+
+	// serve 模式没有 GitHub Actions 运行记录
+	if s.config.GitHub.RunID != "" {
+		b.WriteString(fmt.Sprintf("\n---\n📋 **构建详情**: [查看构建日志](https://github.com/%s/%s/actions/runs/%s)\n",
+			s.config.GitHub.User, s.config.GitHub.Repo, s.config.GitHub.RunID))
+	}
+
+	return b.String()
+}
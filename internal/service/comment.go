@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	githubclient "sync-image/internal/github"
+	"sync-image/internal/source"
+	"sync-image/pkg/utils"
+)
+
+// ProcessComment 处理 Issue 评论中的斜杠命令，评论事件从 GitHub Actions（或兼容的 Gitea Actions）的事件文件读取
+func (s *DefaultSyncService) ProcessComment(ctx context.Context) error {
+	event, err := githubclient.LoadCommentEvent(s.config.GitHub.EventPath)
+	if err != nil {
+		return err
+	}
+	return s.HandleComment(ctx, event)
+}
+
+// HandleComment 处理评论事件中的斜杠命令，命令无效或无权执行时只回应评论，不视为失败
+func (s *DefaultSyncService) HandleComment(ctx context.Context, event *source.CommentEvent) error {
+	issue, comment := event.Request, event.Comment
+
+	// 只处理新评论，忽略 Pull Request 和机器人的评论
+	if event.Action != "created" || issue.PullRequest || comment.Bot {
+		s.logger.Info("忽略评论事件: %s", event.Action)
+		return nil
+	}
+
+	command, ok, err := githubclient.ParseSlashCommand(comment.Body)
+	if !ok {
+		s.logger.Info("Issue #%d 的评论中没有命令", issue.Number)
+		return nil
+	}
+	if err != nil {
+		s.rejectCommand(ctx, issue, comment, err.Error())
+		return nil
+	}
+	if !issue.HasLabel(source.LabelRequest) {
+		s.rejectCommand(ctx, issue, comment, "只能对搬运镜像的 Issue 执行命令")
+		return nil
+	}
+	if !githubclient.CanCommand(event) {
+		s.rejectCommand(ctx, issue, comment, "只有 Issue 作者和维护者可以执行命令")
+		return nil
+	}
+
+	s.logger.Info("Issue #%d 收到 %s 的命令: %s", issue.Number, comment.Author, command)
+
+	switch command.Name {
+	case githubclient.CommandCancel:
+		return s.cancelIssue(ctx, issue, comment)
+	case githubclient.CommandTags:
+		return s.tagIssue(ctx, issue, comment, command.Args)
+	case githubclient.CommandPlatform:
+		return s.retryIssue(ctx, issue, comment, strings.Join(command.Args, ","))
+	default:
+		return s.retryIssue(ctx, issue, comment, "")
+	}
+}
+
+// retryIssue 重新同步已关闭的 Issue，platform 不为空时使用指定架构
+func (s *DefaultSyncService) retryIssue(ctx context.Context, issue *source.Request, comment *source.Comment, platform string) error {
+	if issue.IsOpen() {
+		s.rejectCommand(ctx, issue, comment, "Issue 尚未处理，已在队列中等待同步")
+		return nil
+	}
+	if platform == "" && !issue.HasLabel("failed") && !issue.HasLabel(githubclient.LabelRateLimited) {
+		s.rejectCommand(ctx, issue, comment, "只能重试同步失败或超出限制未处理的 Issue，需要其他架构时使用 /platform")
+		return nil
+	}
+	s.acceptCommand(ctx, issue, comment)
+
+	if err := s.loadMappingIndex(ctx); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	s.issueProcessor.ResetIssue(ctx, issue)
+	summary := &RunSummary{Issues: 1, Outcomes: s.processSingleIssue(ctx, issue, platform, s.issueProcessor.NewStatusComment(issue))}
+	summary.Duration = time.Since(started)
+	s.reportSummary(summary)
+
+	if failed := summary.Failed(); failed > 0 {
+		return fmt.Errorf("Issue #%d 有 %d 个镜像同步失败", issue.Number, failed)
+	}
+	return nil
+}
+
+// tagIssue 为同步成功的 Issue 中的所有目标镜像添加额外标签，不重新同步镜像
+// 目标镜像从本工具发布的同步记录中读取，不解析可被编辑的 Issue 内容；只有维护者可以覆盖已指向其他镜像的标签
+func (s *DefaultSyncService) tagIssue(ctx context.Context, issue *source.Request, comment *source.Comment, tags []string) error {
+	if issue.IsOpen() || !issue.HasLabel("success") {
+		s.rejectCommand(ctx, issue, comment, "只能为同步成功的 Issue 添加标签，失败的 Issue 请先 /retry")
+		return nil
+	}
+
+	records, err := s.issueProcessor.ListSyncRecords(ctx, issue.Number)
+	if err != nil {
+		return err
+	}
+	// 重试会留下多条记录，每个目标镜像只处理一次
+	var targets []string
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if record.Target != "" && !seen[record.Target] {
+			seen[record.Target] = true
+			targets = append(targets, record.Target)
+		}
+	}
+	if len(targets) == 0 {
+		s.rejectCommand(ctx, issue, comment, "Issue 中没有同步记录，无法确定要添加标签的镜像")
+		return nil
+	}
+	s.acceptCommand(ctx, issue, comment)
+
+	var b strings.Builder
+	var failed error
+	for _, target := range targets {
+		if err := s.tagImage(ctx, target, tags, comment.Maintainer); err != nil {
+			failed = err
+			b.WriteString(fmt.Sprintf("- ❌ `%s`: %v\n", target, err))
+			continue
+		}
+
+		name := target
+		if ref, err := utils.ParseReference(name); err == nil {
+			name = ref.Name()
+		}
+		for _, tag := range tags {
+			b.WriteString(fmt.Sprintf("- ✅ `%s:%s`\n", name, tag))
+		}
+	}
+
+	result := "**✅ 标签添加完成**\n\n"
+	if failed != nil {
+		result = "**❌ 标签添加失败**\n\n"
+	}
+	if _, err := s.requestSource.CreateComment(ctx, issue, result+b.String()); err != nil {
+		s.logger.Warn("添加标签结果评论失败: %v", err)
+	}
+
+	if failed != nil {
+		return fmt.Errorf("Issue #%d 添加标签失败: %w", issue.Number, failed)
+	}
+	return nil
+}
+
+// cancelIssue 取消尚未处理的 Issue，关闭后不会再被同步
+func (s *DefaultSyncService) cancelIssue(ctx context.Context, issue *source.Request, comment *source.Comment) error {
+	if !issue.IsOpen() {
+		s.rejectCommand(ctx, issue, comment, "Issue 已处理完成，无法取消")
+		return nil
+	}
+	s.acceptCommand(ctx, issue, comment)
+
+	if _, err := s.requestSource.CreateComment(ctx, issue, fmt.Sprintf("@%s 已取消同步", comment.Author)); err != nil {
+		s.logger.Warn("添加取消评论失败: %v", err)
+	}
+	if err := s.requestSource.AddLabels(ctx, issue, []string{"cancelled"}); err != nil {
+		s.logger.Warn("添加标签失败: %v", err)
+	}
+	return s.requestSource.CloseRequest(ctx, issue)
+}
+
+// acceptCommand 以表情回应已接受的命令
+func (s *DefaultSyncService) acceptCommand(ctx context.Context, issue *source.Request, comment *source.Comment) {
+	if err := s.requestSource.AddReaction(ctx, issue, comment.ID, githubclient.ReactionAccepted); err != nil {
+		s.logger.Warn("添加评论回应失败: %v", err)
+	}
+}
+
+// rejectCommand 以表情和评论回应无法执行的命令
+func (s *DefaultSyncService) rejectCommand(ctx context.Context, issue *source.Request, comment *source.Comment, reason string) {
+	s.logger.Info("Issue #%d 的命令未执行: %s", issue.Number, reason)
+
+	if err := s.requestSource.AddReaction(ctx, issue, comment.ID, githubclient.ReactionRejected); err != nil {
+		s.logger.Warn("添加评论回应失败: %v", err)
+	}
+	if _, err := s.requestSource.CreateComment(ctx, issue, fmt.Sprintf("@%s 命令未执行: %s", comment.Author, reason)); err != nil {
+		s.logger.Warn("添加评论失败: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	githubclient "sync-image/internal/github"
+	"sync-image/internal/registry"
+)
+
+// checkHistory 解析上游镜像摘要并查找之前 Issue 的同步记录，结果写入 result
+// 目标镜像、架构和上游摘要都相同且目标镜像仍与上游一致时记为 duplicate；只有上游摘要不同时记为 previous
+// 查找失败不影响同步，只输出警告
+func (s *DefaultSyncService) checkHistory(ctx context.Context, issueNumber int, result *imageResult) {
+	digest, err := s.manifestDigest(ctx, result.transformed.UpstreamImage)
+	if err != nil {
+		s.logger.Warn("获取上游镜像摘要失败，跳过重复请求检查: %v", err)
+		return
+	}
+	result.digest = digest
+
+	records, err := s.issueProcessor.FindSyncRecords(ctx, result.transformed.SourceImage)
+	if err != nil {
+		s.logger.Warn("查找历史同步记录失败，跳过重复请求检查: %v", err)
+		return
+	}
+
+	platform := s.effectivePlatform(result.request.Platform)
+	for i := range records {
+		record := &records[i]
+		if record.Issue == issueNumber || record.Target != result.transformed.TargetImage || record.Platform != platform {
+			continue
+		}
+
+		if record.Digest != digest {
+			if result.previous == nil {
+				result.previous = record
+			}
+			continue
+		}
+
+		// 目标镜像可能已被清理或覆盖
+		if s.targetMatches(ctx, result.transformed.UpstreamImage, record.Target, platform) {
+			s.logger.Info("镜像 %s 已在 Issue #%d 同步过且上游未变化", record.Source, record.Issue)
+			result.duplicate = record
+			return
+		}
+		s.logger.Info("Issue #%d 同步的目标镜像 %s 已不存在或与上游不一致，重新同步", record.Issue, record.Target)
+	}
+}
+
+// targetMatches 检查目标镜像是否存在且与上游一致，未配置镜像校验器时只检查目标镜像存在
+func (s *DefaultSyncService) targetMatches(ctx context.Context, upstreamImage, targetImage, platform string) bool {
+	if s.verifier == nil {
+		ref, err := registry.ParseImageReference(targetImage)
+		if err != nil {
+			return false
+		}
+		_, err = s.registryClient.HeadManifest(ctx, ref)
+		return err == nil
+	}
+
+	// 与 compareReconcileItem 相同，不比较 config 摘要
+	diffs, err := s.verifier.Compare(ctx, upstreamImage, targetImage, registry.VerifyOptions{
+		Platforms: strings.Split(platform, ","),
+	})
+	return err == nil && len(diffs) == 0
+}
+
+// manifestDigest 返回镜像的清单摘要，HEAD 请求没有返回摘要时下载清单计算
+func (s *DefaultSyncService) manifestDigest(ctx context.Context, image string) (string, error) {
+	ref, err := registry.ParseImageReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	if desc, err := s.registryClient.HeadManifest(ctx, ref); err == nil && desc.Digest != "" {
+		return desc.Digest, nil
+	}
+	manifest, err := s.registryClient.GetManifest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return manifest.Digest, nil
+}
+
+// syncRecord 返回同步成功的镜像的隐藏记录标记，缺少上游摘要时返回空字符串
+func (s *DefaultSyncService) syncRecord(result *imageResult) string {
+	if result.transformed == nil || result.digest == "" {
+		return ""
+	}
+
+	return githubclient.FormatSyncRecord(githubclient.SyncRecord{
+		Source:   result.transformed.SourceImage,
+		Target:   result.transformed.TargetImage,
+		Platform: s.effectivePlatform(result.request.Platform),
+		Digest:   result.digest,
+		Bytes:    result.bytes,
+	})
+}
+
+// measureImage 统计同步的数据量，写入同步记录供每日数据量限制使用，未启用该限制时跳过
+// 统计失败只输出警告，该镜像不计入用量
+func (s *DefaultSyncService) measureImage(ctx context.Context, result *imageResult) {
+	if s.config.GitHub.Limits.BytesLimit() == 0 {
+		return
+	}
+
+	ref, err := registry.ParseImageReference(result.transformed.UpstreamImage)
+	if err != nil {
+		s.logger.Warn("统计同步数据量失败: %v", err)
+		return
+	}
+	inspection, err := s.registryClient.Inspect(ctx, ref, strings.Split(s.effectivePlatform(result.request.Platform), ","))
+	if err != nil {
+		s.logger.Warn("统计同步数据量失败: %v", err)
+		return
+	}
+	for _, image := range inspection.Images {
+		result.bytes += image.Size()
+	}
+}
+
+// effectivePlatform 返回实际请求的架构，未指定时使用默认配置
+func (s *DefaultSyncService) effectivePlatform(platform string) string {
+	if platform != "" {
+		return platform
+	}
+	return s.config.Platforms
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sync-image/internal/docker"
+	"sync-image/internal/registry"
+	"sync-image/internal/source"
+	"sync-image/pkg/utils"
+)
+
+// SyncPlan 同步计划（dry-run 结果）
+type SyncPlan struct {
+	IssueNumber        int
+	OriginalImage      string
+	SourceImage        string
+	UpstreamImage      string // 重定向后实际拉取的上游镜像
+	TargetImage        string
+	ExtraTags          []string
+	Destination        string
+	Rules              []string
+	UpstreamDigest     string
+	RequestedPlatforms []string
+	UpstreamPlatforms  []string
+	CopyPlatforms      []string
+	Blobs              int
+	PresentBlobs       int
+	TotalBytes         int64
+	MissingBytes       int64
+	RegistryType       string
+	PostProcessors     []string
+	Verify             bool
+	Violations         []string
+}
+
+// PlanIssues 生成待处理 Issue 的同步计划，不推送、不打标签、不关闭 Issue
+func (s *DefaultSyncService) PlanIssues(ctx context.Context) error {
+	s.logger.Info("开始生成同步计划（dry-run）")
+
+	issues, err := s.requestSource.PendingRequests(ctx)
+	if err != nil {
+		return fmt.Errorf("获取待处理 Issues 失败: %w", err)
+	}
+
+	if len(issues) == 0 {
+		s.logger.Info("暂无需要搬运的镜像")
+		return nil
+	}
+
+	if err := s.loadMappingIndex(ctx); err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		var reports []string
+		for _, plan := range s.planIssue(ctx, issue) {
+			reports = append(reports, plan.Render())
+		}
+		report := strings.Join(reports, "\n")
+		fmt.Println(report)
+
+		if s.config.App.DryRunComment {
+			if _, err := s.requestSource.CreateComment(ctx, issue, report); err != nil {
+				s.logger.Warn("添加同步计划评论失败: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// planIssue 对单个 Issue 执行请求者限制检查和解析，为其中每个镜像生成同步计划
+// 请求者超出限制或无法检查限制时实际处理不会同步任何镜像，只返回一个记录原因的计划
+func (s *DefaultSyncService) planIssue(ctx context.Context, issue *source.Request) []*SyncPlan {
+	if s.config.GitHub.Limits.Enabled() {
+		limit, err := s.issueProcessor.CheckRequester(ctx, issue)
+		switch {
+		case err != nil:
+			return []*SyncPlan{s.rejectedPlan(issue, fmt.Sprintf("检查请求者限制失败，Issue 将保持打开: %v", err))}
+		case limit != nil:
+			return []*SyncPlan{s.rejectedPlan(issue, fmt.Sprintf("请求者超出限制，Issue 将被关闭: %s", limit.Reason))}
+		}
+	}
+
+	requests, _, err := s.issueProcessor.ParseIssue(issue)
+	if err != nil {
+		return []*SyncPlan{s.rejectedPlan(issue, err.Error())}
+	}
+
+	plans := make([]*SyncPlan, 0, len(requests))
+	for _, request := range requests {
+		plans = append(plans, s.planImage(ctx, issue.Number, request))
+	}
+	return plans
+}
+
+// rejectedPlan 返回不会同步任何镜像的 Issue 的计划
+func (s *DefaultSyncService) rejectedPlan(issue *source.Request, reason string) *SyncPlan {
+	return &SyncPlan{IssueNumber: issue.Number, Verify: s.config.Verify.Enabled, Violations: []string{reason}}
+}
+
+// planImage 对单个镜像执行名称转换和校验，并解析上游镜像
+func (s *DefaultSyncService) planImage(ctx context.Context, issueNumber int, request utils.ImageRequest) *SyncPlan {
+	plan := &SyncPlan{
+		IssueNumber:   issueNumber,
+		OriginalImage: request.Image,
+		ExtraTags:     request.Tags,
+		Verify:        s.config.Verify.Enabled,
+	}
+
+	requested := s.config.Platforms
+	if request.Platform != "" {
+		requested = request.Platform
+	}
+	for _, p := range strings.Split(requested, ",") {
+		plan.RequestedPlatforms = append(plan.RequestedPlatforms, strings.TrimSpace(p))
+	}
+
+	transformed, err := s.imageTransformer.TransformWithRepository(request.Image, docker.TargetFromConfig(s.config.GetEffectiveGenericConfig()), request.Target)
+	if err != nil {
+		plan.Violations = append(plan.Violations, fmt.Sprintf("镜像名称转换失败: %v", err))
+		return plan
+	}
+	plan.SourceImage, plan.TargetImage = transformed.SourceImage, transformed.TargetImage
+	plan.UpstreamImage = transformed.UpstreamImage
+	plan.Destination = transformed.Destination
+	plan.Rules = transformed.Rules
+	if err := s.imageTransformer.ValidateTransformation(plan.SourceImage, plan.TargetImage); err != nil {
+		plan.Violations = append(plan.Violations, fmt.Sprintf("镜像名称验证失败: %v", err))
+		return plan
+	}
+
+	registryURL := s.extractRegistryURL(plan.TargetImage)
+	plan.RegistryType = s.registryFactory.DetectRegistryType(registryURL).String()
+	plan.PostProcessors = s.registryFactory.PlannedPostProcessors(plan.TargetImage, registryURL)
+
+	s.planUpstream(ctx, plan)
+	return plan
+}
+
+// planUpstream 解析上游镜像并统计目标仓库中已存在的 blob
+func (s *DefaultSyncService) planUpstream(ctx context.Context, plan *SyncPlan) {
+	sourceRef, err := registry.ParseImageReference(plan.UpstreamImage)
+	if err != nil {
+		plan.Violations = append(plan.Violations, err.Error())
+		return
+	}
+
+	inspection, err := s.registryClient.Inspect(ctx, sourceRef, plan.RequestedPlatforms)
+	if err != nil {
+		plan.Violations = append(plan.Violations, fmt.Sprintf("无法解析上游镜像: %v", err))
+		return
+	}
+	plan.UpstreamDigest = inspection.Digest
+	plan.UpstreamPlatforms = inspection.AvailablePlatforms
+
+	if len(inspection.Images) == 0 {
+		plan.Violations = append(plan.Violations, fmt.Sprintf("上游镜像不支持任何请求的架构。上游支持: %v, 请求: %v",
+			inspection.AvailablePlatforms, plan.RequestedPlatforms))
+		return
+	}
+
+	targetRef, err := registry.ParseImageReference(plan.TargetImage)
+	if err != nil {
+		plan.Violations = append(plan.Violations, err.Error())
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, image := range inspection.Images {
+		plan.CopyPlatforms = append(plan.CopyPlatforms, image.Platform)
+
+		for _, blob := range image.Blobs {
+			if seen[blob.Digest] {
+				continue
+			}
+			seen[blob.Digest] = true
+			plan.Blobs++
+			plan.TotalBytes += blob.Size
+
+			exists, err := s.registryClient.BlobExists(ctx, targetRef, blob.Digest)
+			if err != nil {
+				s.logger.Debug("检查目标仓库 blob 失败: %v", err)
+			}
+			if exists {
+				plan.PresentBlobs++
+			} else {
+				plan.MissingBytes += blob.Size
+			}
+		}
+	}
+}
+
+// Render 将同步计划渲染为 Markdown
+func (p *SyncPlan) Render() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("**🧪 同步计划（dry-run）** Issue #%d\n\n", p.IssueNumber))
+	b.WriteString("> 仅解析和检查，不会推送镜像、添加标签或关闭 Issue\n\n")
+
+	b.WriteString("| 项目 | 值 |\n")
+	b.WriteString("| --- | --- |\n")
+	writeRow(&b, "原始请求", code(p.OriginalImage))
+	writeRow(&b, "源镜像", code(p.SourceImage))
+	if p.UpstreamImage != "" && p.UpstreamImage != p.SourceImage {
+		writeRow(&b, "上游镜像", code(p.UpstreamImage)+"（仓库已重定向，目标镜像名称保持不变）")
+	}
+	writeRow(&b, "目标镜像", code(p.TargetImage))
+	if len(p.ExtraTags) > 0 {
+		writeRow(&b, "额外标签", code(strings.Join(p.ExtraTags, ", ")))
+	}
+	if p.Destination != "" {
+		writeRow(&b, "目标仓库", code(p.Destination))
+	}
+	writeRow(&b, "转换规则", code(strings.Join(p.Rules, ", ")))
+	writeRow(&b, "上游摘要", code(p.UpstreamDigest))
+	writeRow(&b, "上游架构", code(strings.Join(p.UpstreamPlatforms, ", ")))
+	writeRow(&b, "请求架构", code(strings.Join(p.RequestedPlatforms, ", ")))
+	writeRow(&b, "将同步架构", code(strings.Join(p.CopyPlatforms, ", ")))
+	if p.Blobs > 0 {
+		writeRow(&b, "Blob", fmt.Sprintf("共 %d 个（%s），目标仓库已存在 %d 个，需要复制 %d 个（%s）",
+			p.Blobs, utils.FormatBytes(p.TotalBytes), p.PresentBlobs, p.Blobs-p.PresentBlobs, utils.FormatBytes(p.MissingBytes)))
+	}
+	writeRow(&b, "仓库类型", code(p.RegistryType))
+	if len(p.PostProcessors) > 0 {
+		writeRow(&b, "后处理器", strings.Join(p.PostProcessors, ", "))
+	} else {
+		writeRow(&b, "后处理器", "无")
+	}
+	if p.Verify {
+		writeRow(&b, "推送后校验", "启用")
+	} else {
+		writeRow(&b, "推送后校验", "关闭")
+	}
+
+	if len(p.Violations) > 0 {
+		b.WriteString("\n**⚠️ 检查未通过**:\n")
+		for _, violation := range p.Violations {
+			b.WriteString(fmt.Sprintf("- %s\n", violation))
+		}
+	} else {
+		b.WriteString("\n✅ 检查通过，正式运行时将按以上计划同步\n")
+	}
+
+	return b.String()
+}
+
+// writeRow 写入表格行
+func writeRow(b *strings.Builder, name, value string) {
+	if value == "" {
+		value = "-"
+	}
+	b.WriteString(fmt.Sprintf("| %s | %s |\n", name, value))
+}
+
+// code 以行内代码格式输出非空值
+func code(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "`" + value + "`"
+}
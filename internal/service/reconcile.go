@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"sync-image/internal/docker"
+	githubclient "sync-image/internal/github"
+	"sync-image/internal/gitops"
+	"sync-image/internal/registry"
+	"sync-image/pkg/utils"
+)
+
+// 声明式同步发布的 Check Run 名称
+const (
+	reconcilePlanCheck = "reconcile / plan"
+	reconcileSyncCheck = "reconcile / sync"
+)
+
+// ReconcileAction 声明式同步中单个镜像需要执行的操作
+type ReconcileAction string
+
+const (
+	ReconcileCreate    ReconcileAction = "create"    // 目标镜像不存在
+	ReconcileUpdate    ReconcileAction = "update"    // 目标镜像与上游不一致
+	ReconcileUnchanged ReconcileAction = "unchanged" // 目标镜像与上游一致
+	ReconcileRemove    ReconcileAction = "remove"    // 已从清单中移除，不再同步，目标镜像保留
+	ReconcileInvalid   ReconcileAction = "invalid"   // 无法解析、转换或比较
+)
+
+// symbol 返回计划中表示操作的前缀符号
+func (a ReconcileAction) symbol() string {
+	switch a {
+	case ReconcileCreate:
+		return "+"
+	case ReconcileUpdate:
+		return "~"
+	case ReconcileUnchanged:
+		return "="
+	case ReconcileRemove:
+		return "-"
+	}
+	return "!"
+}
+
+// label 返回操作的中文名称
+func (a ReconcileAction) label() string {
+	switch a {
+	case ReconcileCreate:
+		return "新增"
+	case ReconcileUpdate:
+		return "更新"
+	case ReconcileUnchanged:
+		return "未变化"
+	case ReconcileRemove:
+		return "移除"
+	}
+	return "错误"
+}
+
+// ReconcileItem 镜像清单展开后的单个镜像
+type ReconcileItem struct {
+	Image         string // 清单中的镜像和标签
+	SourceImage   string
+	UpstreamImage string
+	TargetImage   string
+	Destination   string
+	Platforms     string
+	Action        ReconcileAction
+	Diffs         []string // 目标镜像与上游的差异，只在 update 时有值
+	Error         string   // 计划或同步失败的原因
+	Synced        bool     // 已同步完成
+	Duration      time.Duration
+}
+
+// ReconcilePlan 声明式同步计划
+type ReconcilePlan struct {
+	Items []*ReconcileItem
+}
+
+// Count 返回指定操作的镜像数量
+func (p *ReconcilePlan) Count(action ReconcileAction) int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Pending 返回需要同步的镜像
+func (p *ReconcilePlan) Pending() []*ReconcileItem {
+	var pending []*ReconcileItem
+	for _, item := range p.Items {
+		if item.Action == ReconcileCreate || item.Action == ReconcileUpdate {
+			pending = append(pending, item)
+		}
+	}
+	return pending
+}
+
+// Failed 返回计划或同步失败的镜像数量
+func (p *ReconcilePlan) Failed() int {
+	failed := 0
+	for _, item := range p.Items {
+		if item.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Render 以差异格式渲染计划：+ 新增，~ 更新，- 移除，= 未变化，! 错误
+func (p *ReconcilePlan) Render() string {
+	var b strings.Builder
+	for _, item := range p.Items {
+		if item.TargetImage == "" {
+			b.WriteString(fmt.Sprintf("%s %s\n", item.Action.symbol(), item.Image))
+		} else {
+			b.WriteString(fmt.Sprintf("%s %s -> %s [%s]\n", item.Action.symbol(), item.SourceImage, item.TargetImage, item.Platforms))
+		}
+		for _, diff := range item.Diffs {
+			b.WriteString(fmt.Sprintf("    %s\n", diff))
+		}
+		if item.Action == ReconcileInvalid {
+			b.WriteString(fmt.Sprintf("    %s\n", item.Error))
+		}
+	}
+	b.WriteString("\n计划: " + p.Totals() + "\n")
+	return b.String()
+}
+
+// Totals 返回计划中各操作的数量
+func (p *ReconcilePlan) Totals() string {
+	totals := fmt.Sprintf("新增 %d 个，更新 %d 个，", p.Count(ReconcileCreate), p.Count(ReconcileUpdate))
+	if removed := p.Count(ReconcileRemove); removed > 0 {
+		totals += fmt.Sprintf("移除 %d 个，", removed)
+	}
+	return totals + fmt.Sprintf("未变化 %d 个，错误 %d 个", p.Count(ReconcileUnchanged), p.Count(ReconcileInvalid))
+}
+
+// RenderResult 渲染同步结果，只包含需要同步的镜像
+func (p *ReconcilePlan) RenderResult() string {
+	var b strings.Builder
+	for _, item := range p.Pending() {
+		if item.Synced {
+			b.WriteString(fmt.Sprintf("✅ %s -> %s (%s)\n", item.SourceImage, item.TargetImage, item.Duration.Round(time.Second)))
+		} else {
+			b.WriteString(fmt.Sprintf("❌ %s -> %s: %s\n", item.SourceImage, item.TargetImage, item.Error))
+		}
+	}
+	synced, failed := p.syncCounts()
+	b.WriteString(fmt.Sprintf("\n同步: 成功 %d 个，失败 %d 个\n", synced, failed))
+	return b.String()
+}
+
+// syncCounts 返回需要同步的镜像中同步成功和失败的数量
+func (p *ReconcilePlan) syncCounts() (synced, failed int) {
+	for _, item := range p.Pending() {
+		if item.Synced {
+			synced++
+		} else {
+			failed++
+		}
+	}
+	return synced, failed
+}
+
+// PlanReconcile 展开镜像清单中的标签，比较上游和目标仓库，生成同步计划
+// base 为变更前的清单，不为空时其中有、当前清单中没有的目标镜像记为 remove
+// 不推送镜像；单个镜像无法处理时记为 invalid，不影响其他镜像
+func (s *DefaultSyncService) PlanReconcile(ctx context.Context, manifest, base *gitops.Manifest) (*ReconcilePlan, error) {
+	s.logger.Info("开始生成声明式同步计划，共 %d 个镜像条目", len(manifest.Images))
+
+	if err := s.loadMappingIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	plan := &ReconcilePlan{Items: s.expandManifest(ctx, manifest)}
+	targets := make(map[string]bool, len(plan.Items))
+	for _, item := range plan.Items {
+		if item.TargetImage != "" {
+			targets[item.TargetImage] = true
+		}
+		if item.Action == "" {
+			s.compareReconcileItem(ctx, item)
+		}
+	}
+
+	if base != nil {
+		// 目标仓库中的镜像不会被删除，只在计划中列出不再同步的镜像
+		for _, item := range s.expandManifest(ctx, base) {
+			if item.Action == "" && !targets[item.TargetImage] {
+				item.Action = ReconcileRemove
+				plan.Items = append(plan.Items, item)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplyReconcile 按计划同步新增和需要更新的镜像，失败的镜像记录原因后继续处理其他镜像
+// 有镜像同步失败或计划中有错误时返回错误
+func (s *DefaultSyncService) ApplyReconcile(ctx context.Context, plan *ReconcilePlan) error {
+	pending := plan.Pending()
+	s.logger.Info("开始声明式同步，需要同步 %d 个镜像", len(pending))
+
+	for n, item := range pending {
+		s.logger.Info("[%d/%d] 同步 %s -> %s", n+1, len(pending), item.UpstreamImage, item.TargetImage)
+		started := time.Now()
+		if err := s.applyReconcileItem(ctx, item); err != nil {
+			item.Error = err.Error()
+			s.logger.Error("镜像 %s 同步失败: %v", item.SourceImage, err)
+		} else {
+			item.Synced = true
+		}
+		item.Duration = time.Since(started)
+	}
+
+	s.writeStepSummary("### 声明式同步\n\n```diff\n" + plan.Render() + "```\n\n```\n" + plan.RenderResult() + "```\n")
+
+	if failed := plan.Failed(); failed > 0 {
+		return fmt.Errorf("%d 个镜像同步失败", failed)
+	}
+	return nil
+}
+
+// PublishReconcileCheck 在提交上发布声明式同步的 Check Run
+// applied 为 false 时发布同步计划（用于变更清单的 Pull Request），有无法处理的镜像时结论为 failure；
+// 为 true 时发布同步结果（用于合并后的提交），有镜像同步失败时结论为 failure
+func (s *DefaultSyncService) PublishReconcileCheck(ctx context.Context, plan *ReconcilePlan, sha string, applied bool) error {
+	run := &githubclient.CheckRun{
+		Name:       reconcilePlanCheck,
+		HeadSHA:    sha,
+		Conclusion: "success",
+		Title:      plan.Totals(),
+		Text:       "```diff\n" + plan.Render() + "```\n",
+	}
+
+	var b strings.Builder
+	if applied {
+		run.Name = reconcileSyncCheck
+		synced, failed := plan.syncCounts()
+		run.Title = fmt.Sprintf("同步成功 %d 个，失败 %d 个", synced, failed)
+		if plan.Failed() > 0 {
+			run.Conclusion = "failure"
+		}
+		b.WriteString("### 镜像同步结果\n\n```\n" + plan.RenderResult() + "```\n")
+	} else {
+		if plan.Count(ReconcileInvalid) > 0 {
+			run.Conclusion = "failure"
+		}
+		b.WriteString("### 镜像同步计划\n\n")
+		b.WriteString("| 操作 | 数量 |\n| --- | --- |\n")
+		for _, action := range []ReconcileAction{ReconcileCreate, ReconcileUpdate, ReconcileRemove, ReconcileUnchanged, ReconcileInvalid} {
+			b.WriteString(fmt.Sprintf("| `%s` %s | %d |\n", action.symbol(), action.label(), plan.Count(action)))
+		}
+		if plan.Count(ReconcileRemove) > 0 {
+			b.WriteString("\n移除的镜像不再同步，目标仓库中已有的镜像不会被删除\n")
+		}
+	}
+
+	var violations []string
+	for _, item := range plan.Items {
+		if item.Action == ReconcileInvalid {
+			violations = append(violations, fmt.Sprintf("- `%s`: %s", item.Image, item.Error))
+		}
+	}
+	if len(violations) > 0 {
+		b.WriteString("\n**⚠️ 检查未通过**:\n" + strings.Join(violations, "\n") + "\n")
+	}
+	run.Summary = b.String()
+
+	// Check Run 只有 GitHub 支持
+	client, ok := s.requestSource.(githubclient.Client)
+	if !ok {
+		return fmt.Errorf("请求来源 %s 不支持 Check Run", s.requestSource.Type())
+	}
+	return client.CreateCheckRun(ctx, run)
+}
+
+// reconcileTags 返回镜像条目要同步的标签，使用 tag_filter 时读取上游标签列表
+func (s *DefaultSyncService) reconcileTags(ctx context.Context, spec *gitops.ImageSpec) ([]string, error) {
+	var available []string
+	if spec.NeedsTagList() {
+		// 标签从实际拉取的上游仓库读取
+		upstream, _ := utils.RedirectReference(spec.Repository(), s.config.Redirects)
+		ref, err := registry.ParseImageReference(upstream)
+		if err != nil {
+			return nil, err
+		}
+		if available, err = s.registryClient.ListTags(ctx, ref); err != nil {
+			return nil, fmt.Errorf("读取上游标签列表失败: %w", err)
+		}
+	}
+
+	tags := spec.ResolveTags(available)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("tag_filter %q 没有匹配任何上游标签", spec.TagFilter)
+	}
+	return tags, nil
+}
+
+// expandManifest 展开清单中每个条目的标签并转换镜像名称，无法处理的条目记为 invalid，其余条目的 Action 为空
+// 多个条目展开后指向同一个目标镜像时只保留第一个，源镜像不同时记为 invalid
+func (s *DefaultSyncService) expandManifest(ctx context.Context, manifest *gitops.Manifest) []*ReconcileItem {
+	var items []*ReconcileItem
+	seen := make(map[string]string) // 目标镜像 -> 源镜像
+	for i := range manifest.Images {
+		spec := &manifest.Images[i]
+
+		tags, err := s.reconcileTags(ctx, spec)
+		if err != nil {
+			items = append(items, &ReconcileItem{Image: spec.Image, Action: ReconcileInvalid, Error: err.Error()})
+			continue
+		}
+
+		for _, tag := range tags {
+			item := s.transformReconcileItem(spec.Repository()+":"+tag, manifest.EffectiveDestination(spec), spec.Target,
+				manifest.EffectivePlatforms(spec, s.config.Platforms))
+			if item.TargetImage != "" && item.Action == "" {
+				if source, ok := seen[item.TargetImage]; ok {
+					if source == item.SourceImage {
+						continue
+					}
+					item.Action = ReconcileInvalid
+					item.Error = fmt.Sprintf("目标镜像已被 %s 使用", source)
+				} else {
+					seen[item.TargetImage] = item.SourceImage
+				}
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// transformReconcileItem 转换单个镜像的名称，失败时记为 invalid
+func (s *DefaultSyncService) transformReconcileItem(image, destination, repository, platforms string) *ReconcileItem {
+	item := &ReconcileItem{Image: image, Destination: destination, Platforms: platforms}
+
+	var transformed *docker.TransformResult
+	var err error
+	if destination != "" {
+		transformed, err = s.imageTransformer.TransformToDestination(image, destination, repository)
+	} else {
+		transformed, err = s.imageTransformer.TransformWithRepository(image, docker.TargetFromConfig(s.config.GetEffectiveGenericConfig()), repository)
+	}
+	if err == nil {
+		err = s.imageTransformer.ValidateTransformation(transformed.SourceImage, transformed.TargetImage)
+	}
+	if err != nil {
+		item.Action = ReconcileInvalid
+		item.Error = fmt.Sprintf("镜像名称转换失败: %v", err)
+		return item
+	}
+	item.SourceImage, item.UpstreamImage, item.TargetImage = transformed.SourceImage, transformed.UpstreamImage, transformed.TargetImage
+	item.Destination = transformed.Destination
+	return item
+}
+
+// compareReconcileItem 比较目标仓库中的镜像与上游，决定镜像需要执行的操作
+func (s *DefaultSyncService) compareReconcileItem(ctx context.Context, item *ReconcileItem) {
+	if s.verifier == nil {
+		item.Action = ReconcileInvalid
+		item.Error = "未配置镜像校验器，无法比较目标镜像"
+		return
+	}
+
+	// 只按平台集合和 layer 判断是否过期：重新同步会由 buildx 生成新的 config，比较 config 摘要会导致计划永远无法收敛
+	diffs, err := s.verifier.Compare(ctx, item.UpstreamImage, item.TargetImage, registry.VerifyOptions{
+		Platforms: strings.Split(item.Platforms, ","),
+	})
+	switch {
+	case err == registry.ErrNotFound:
+		item.Action = ReconcileCreate
+	case err != nil:
+		item.Action = ReconcileInvalid
+		item.Error = fmt.Sprintf("比较目标镜像失败: %v", err)
+	case len(diffs) > 0:
+		item.Action = ReconcileUpdate
+		item.Diffs = diffs
+	default:
+		item.Action = ReconcileUnchanged
+	}
+}
+
+// applyReconcileItem 构建推送单个镜像，执行后处理和推送后校验并记录映射
+func (s *DefaultSyncService) applyReconcileItem(ctx context.Context, item *ReconcileItem) error {
+	release, err := s.reserveMapping(item.SourceImage, item.TargetImage)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := s.dockerBuilder.BuildAndPush(ctx, item.UpstreamImage, item.TargetImage, item.Platforms); err != nil {
+		return fmt.Errorf("Docker 构建推送失败: %w", err)
+	}
+	if err := s.processImageWithDynamicRegistry(item.TargetImage); err != nil {
+		return fmt.Errorf("设置镜像权限失败: %w", err)
+	}
+	if err := s.verifyImage(ctx, item.UpstreamImage, item.TargetImage, item.Platforms); err != nil {
+		return fmt.Errorf("推送后校验失败: %w", err)
+	}
+	return s.recordMapping(ctx, item.SourceImage, item.TargetImage)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// IssueOutcome 单个 Issue 中一个镜像的处理结果，Issue 解析失败时只有一条结果
+type IssueOutcome struct {
+	Number      int
+	SourceImage string // 标准化后的源镜像，解析失败时为空
+	TargetImage string // 目标镜像，转换失败时为空
+	Success     bool
+	Skipped     bool // 请求者超出限制或 Issue 已取消，Issue 未处理
+	Error       string
+	Duration    time.Duration
+}
+
+// RunSummary 一次运行中所有 Issue 的处理汇总
+type RunSummary struct {
+	Issues   int            // 处理的 Issue 数量
+	Outcomes []IssueOutcome // 按 Issue 创建时间和镜像在 Issue 中的顺序排序
+	Duration time.Duration
+}
+
+// Failed 返回处理失败的镜像数量，不包括未处理的请求
+func (r *RunSummary) Failed() int {
+	failed := 0
+	for _, outcome := range r.Outcomes {
+		if !outcome.Success && !outcome.Skipped {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Skipped 返回因超出限制或已取消未处理的请求数量
+func (r *RunSummary) Skipped() int {
+	skipped := 0
+	for _, outcome := range r.Outcomes {
+		if outcome.Skipped {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// Render 将汇总渲染为 Markdown 表格
+func (r *RunSummary) Render() string {
+	var b strings.Builder
+
+	b.WriteString("### 镜像同步汇总\n\n")
+	b.WriteString(fmt.Sprintf("共处理 %d 个 Issue 的 %d 个镜像，成功 %d 个，失败 %d 个，耗时 %s\n\n",
+		r.Issues, len(r.Outcomes)-r.Skipped(), len(r.Outcomes)-r.Failed()-r.Skipped(), r.Failed(), r.Duration.Round(time.Second)))
+	if skipped := r.Skipped(); skipped > 0 {
+		b.WriteString(fmt.Sprintf("另有 %d 个 Issue 因请求者超出限制或已取消未处理\n\n", skipped))
+	}
+
+	b.WriteString("| Issue | 源镜像 | 目标镜像 | 结果 | 耗时 |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, outcome := range r.Outcomes {
+		status := "✅ 成功"
+		if outcome.Skipped {
+			status = "⏸️ 未处理: " + strings.NewReplacer("\n", " ", "|", "\\|").Replace(outcome.Error)
+		} else if !outcome.Success {
+			// 表格单元格中不能出现换行和竖线
+			status = "❌ " + strings.NewReplacer("\n", " ", "|", "\\|").Replace(outcome.Error)
+		}
+		b.WriteString(fmt.Sprintf("| #%d | %s | %s | %s | %s |\n",
+			outcome.Number, orDash(code(outcome.SourceImage)), orDash(code(outcome.TargetImage)), status, outcome.Duration.Round(time.Second)))
+	}
+
+	return b.String()
+}
+
+// reportSummary 输出运行汇总，在 GitHub Actions 中同时写入任务摘要
+func (s *DefaultSyncService) reportSummary(summary *RunSummary) {
+	s.logger.Info("处理完成: 共 %d 个 Issue 的 %d 个镜像，成功 %d 个，失败 %d 个，未处理 %d 个，耗时 %s",
+		summary.Issues, len(summary.Outcomes)-summary.Skipped(), len(summary.Outcomes)-summary.Failed()-summary.Skipped(), summary.Failed(), summary.Skipped(), summary.Duration.Round(time.Second))
+	for _, outcome := range summary.Outcomes {
+		if outcome.Success {
+			s.logger.Info("  #%d %s -> %s", outcome.Number, outcome.SourceImage, outcome.TargetImage)
+		} else if outcome.Skipped {
+			s.logger.Info("  #%d 未处理: %s", outcome.Number, outcome.Error)
+		} else {
+			s.logger.Info("  #%d 失败: %s", outcome.Number, outcome.Error)
+		}
+	}
+
+	s.writeStepSummary(summary.Render())
+}
+
+// writeStepSummary 在 GitHub Actions 中将内容追加到任务摘要，不在 Actions 中运行时跳过
+func (s *DefaultSyncService) writeStepSummary(content string) {
+	// GitHub Actions 通过 GITHUB_STEP_SUMMARY 指定任务摘要文件
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		s.logger.Warn("写入任务摘要失败: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		s.logger.Warn("写入任务摘要失败: %v", err)
+	}
+}
+
+// orDash 空值输出为 "-"
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
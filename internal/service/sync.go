@@ -3,16 +3,20 @@ package service
 import (
 	"bytes"
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strings"
+	"sync"
 	"text/template"
-
-	"github.com/google/go-github/v47/github"
+	"time"
 
 	"sync-image/internal/config"
 	"sync-image/internal/docker"
 	githubclient "sync-image/internal/github"
+	"sync-image/internal/gitops"
+	"sync-image/internal/mapping"
 	"sync-image/internal/registry"
+	"sync-image/internal/source"
 	"sync-image/pkg/errors"
 	"sync-image/pkg/logger"
 )
@@ -20,47 +24,65 @@ import (
 // SyncService 同步服务接口
 type SyncService interface {
 	ProcessIssues(ctx context.Context) error
+	ProcessIssue(ctx context.Context, number int) error
+	ProcessComment(ctx context.Context) error
+	HandleComment(ctx context.Context, event *source.CommentEvent) error
+	PlanIssues(ctx context.Context) error
+	PlanReconcile(ctx context.Context, manifest, base *gitops.Manifest) (*ReconcilePlan, error)
+	ApplyReconcile(ctx context.Context, plan *ReconcilePlan) error
+	PublishReconcileCheck(ctx context.Context, plan *ReconcilePlan, sha string, applied bool) error
 	Cleanup() error
 }
 
 // DefaultSyncService 默认同步服务实现
 type DefaultSyncService struct {
 	config           *config.Config
-	githubClient     githubclient.Client
+	requestSource    source.RequestSource
 	issueProcessor   *githubclient.IssueProcessor
 	dockerBuilder    docker.Builder
 	imageTransformer *docker.ImageTransformer
 	registryFactory  *registry.RegistryManagerFactory
+	registryClient   *registry.Client
+	verifier         *registry.Verifier
+	mappingIndex     *mapping.Index
 	logger           logger.Logger
 }
 
 // NewSyncService 创建新的同步服务
 func NewSyncService(
 	cfg *config.Config,
-	githubClient githubclient.Client,
+	requestSource source.RequestSource,
 	issueProcessor *githubclient.IssueProcessor,
 	dockerBuilder docker.Builder,
 	imageTransformer *docker.ImageTransformer,
 	registryFactory *registry.RegistryManagerFactory,
+	registryClient *registry.Client,
+	verifier *registry.Verifier,
+	mappingIndex *mapping.Index,
 	log logger.Logger,
 ) SyncService {
 	return &DefaultSyncService{
 		config:           cfg,
-		githubClient:     githubClient,
+		requestSource:    requestSource,
 		issueProcessor:   issueProcessor,
 		dockerBuilder:    dockerBuilder,
 		imageTransformer: imageTransformer,
 		registryFactory:  registryFactory,
+		registryClient:   registryClient,
+		verifier:         verifier,
+		mappingIndex:     mappingIndex,
 		logger:           log,
 	}
 }
 
-// ProcessIssues 处理 Issues
+// ProcessIssues 处理所有待处理的 Issues
+// 按创建时间从早到晚分发给 app.workers 个并发 worker，全部处理完成后输出汇总
 func (s *DefaultSyncService) ProcessIssues(ctx context.Context) error {
 	s.logger.Info("开始处理 Issues")
+	started := time.Now()
 
 	// 获取待处理的 Issues
-	issues, err := s.githubClient.GetPendingIssues(ctx)
+	issues, err := s.requestSource.PendingRequests(ctx)
 	if err != nil {
 		return fmt.Errorf("获取待处理 Issues 失败: %w", err)
 	}
@@ -70,88 +92,338 @@ func (s *DefaultSyncService) ProcessIssues(ctx context.Context) error {
 		return nil
 	}
 
-	// 处理第一个 Issue（每次只处理一个）
-	issue := issues[0]
-	return s.processSingleIssue(ctx, issue)
-}
+	if err := s.loadMappingIndex(ctx); err != nil {
+		return err
+	}
 
-// processSingleIssue 处理单个 Issue
-func (s *DefaultSyncService) processSingleIssue(ctx context.Context, issue *github.Issue) error {
-	s.logger.Info("开始处理 Issue #%d", issue.GetNumber())
+	workers := s.config.App.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(issues) {
+		workers = len(issues)
+	}
+	s.logger.Info("使用 %d 个 worker 处理 %d 个 Issue", workers, len(issues))
 
-	var (
-		sourceImage string
-		targetImage string
-		platform    string
-		syncErr     error
-	)
+	// 每个 Issue 一条状态评论，先标记为排队中
+	statuses := make([]*githubclient.StatusComment, len(issues))
+	for i, issue := range issues {
+		statuses[i] = s.issueProcessor.NewStatusComment(issue)
+		statuses[i].Stage(ctx, "已进入队列（第 %d/%d 个）", i+1, len(issues))
+	}
 
-	// 处理 Issue 并获取镜像信息
-	originalImage, platform, err := s.issueProcessor.ProcessIssue(ctx, issue)
-	if err != nil {
-		syncErr = err
-	} else {
-		// 执行镜像同步
-		sourceImage, targetImage, syncErr = s.syncImage(ctx, originalImage, platform)
+	outcomes := make([][]IssueOutcome, len(issues))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				issue, ok := s.refreshIssue(ctx, issues[i])
+				if !ok {
+					outcomes[i] = []IssueOutcome{{Number: issue.Number, Skipped: true, Error: "Issue 已关闭或取消"}}
+					if err := statuses[i].Finish(ctx, "**⏹️ 已取消同步**"); err != nil {
+						s.logger.Warn("更新状态评论失败: %v", err)
+					}
+					continue
+				}
+				outcomes[i] = s.processSingleIssue(ctx, issue, "", statuses[i])
+			}
+		}()
+	}
+	for i := range issues {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	summary := &RunSummary{Issues: len(issues), Duration: time.Since(started)}
+	failed := 0
+	for _, issueOutcomes := range outcomes {
+		summary.Outcomes = append(summary.Outcomes, issueOutcomes...)
+		for _, outcome := range issueOutcomes {
+			if !outcome.Success && !outcome.Skipped {
+				failed++
+				break
+			}
+		}
 	}
 
-	// 生成结果报告
-	result := s.generateResult(sourceImage, targetImage, platform, syncErr == nil, syncErr)
+	s.reportSummary(summary)
 
-	// 完成 Issue 处理
-	if finishErr := s.issueProcessor.FinishIssue(ctx, issue, syncErr == nil, result, platform); finishErr != nil {
-		s.logger.Error("完成 Issue 处理失败: %v", finishErr)
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 个 Issue 处理失败", failed, len(issues))
+	}
+	return nil
+}
+
+// refreshIssue 在开始处理前重新获取 Issue，排队期间被关闭或 /cancel 取消时返回 false
+// 重新获取失败时按之前获取的内容处理
+func (s *DefaultSyncService) refreshIssue(ctx context.Context, issue *source.Request) (*source.Request, bool) {
+	latest, err := s.requestSource.GetRequest(ctx, issue.Number)
+	if err != nil {
+		s.logger.Warn("重新获取 Issue #%d 失败，按之前获取的内容处理: %v", issue.Number, err)
+		return issue, true
+	}
+	if !latest.IsOpen() || latest.HasLabel("cancelled") {
+		s.logger.Info("Issue #%d 已关闭或取消，跳过", issue.Number)
+		return latest, false
+	}
+	return latest, true
+}
+
+// ProcessIssue 处理指定的 Issue，先重新获取 Issue，已关闭或不是搬运请求时跳过
+func (s *DefaultSyncService) ProcessIssue(ctx context.Context, number int) error {
+	issue, err := s.requestSource.GetRequest(ctx, number)
+	if err != nil {
+		return err
+	}
+	if !issue.IsOpen() || issue.PullRequest || !issue.HasLabel(source.LabelRequest) {
+		s.logger.Info("Issue #%d 不是待处理的搬运请求，跳过", number)
+		return nil
 	}
 
-	if syncErr != nil {
-		return fmt.Errorf("镜像同步失败: %w", syncErr)
+	if err := s.loadMappingIndex(ctx); err != nil {
+		return err
 	}
 
-	s.logger.Info("Issue #%d 处理完成", issue.GetNumber())
+	started := time.Now()
+	summary := &RunSummary{Issues: 1, Outcomes: s.processSingleIssue(ctx, issue, "", s.issueProcessor.NewStatusComment(issue))}
+	summary.Duration = time.Since(started)
+	s.reportSummary(summary)
+
+	if failed := summary.Failed(); failed > 0 {
+		return fmt.Errorf("Issue #%d 有 %d 个镜像同步失败", number, failed)
+	}
 	return nil
 }
 
-// syncImage 同步镜像
-func (s *DefaultSyncService) syncImage(ctx context.Context, originalImage, platform string) (sourceImage, targetImage string, err error) {
-	s.logger.Info("开始同步镜像: %s", originalImage)
+// processSingleIssue 处理单个 Issue，失败时也会评论、打标签并关闭 Issue（检查请求者限制失败时保持打开）
+// Issue 中的多个镜像依次同步，每个镜像返回一条处理结果；platform 不为空时替换所有镜像请求的架构
+// 各阶段的进度写入状态评论，处理完成后状态评论替换为结果
+func (s *DefaultSyncService) processSingleIssue(ctx context.Context, issue *source.Request, platform string, status *githubclient.StatusComment) []IssueOutcome {
+	s.logger.Info("开始处理 Issue #%d", issue.Number)
+	started := time.Now()
 
-	// 获取有效的通用配置
-	genericConfig := s.config.GetEffectiveGenericConfig()
-	var registry, namespace string
-	if genericConfig != nil {
-		registry = genericConfig.Registry
-		namespace = genericConfig.Namespace
+	// 处理 Issue 并获取镜像信息
+	requests, structured, err := s.issueProcessor.ProcessIssue(ctx, issue, status)
+	var limit *githubclient.LimitError
+	if stderrors.As(err, &limit) {
+		// Issue 已评论说明并关闭，不计为失败
+		return []IssueOutcome{{
+			Number:   issue.Number,
+			Skipped:  true,
+			Error:    limit.Reason,
+			Duration: time.Since(started),
+		}}
+	}
+	var check *githubclient.LimitCheckError
+	if stderrors.As(err, &check) {
+		// Issue 保持打开，下次运行时重新处理
+		s.logger.Error("Issue #%d 暂不处理: %v", issue.Number, err)
+		return []IssueOutcome{{
+			Number:   issue.Number,
+			Error:    err.Error(),
+			Duration: time.Since(started),
+		}}
+	}
+	if err != nil {
+		result := s.generateResult(&imageResult{err: err})
+		if finishErr := s.issueProcessor.FinishIssue(ctx, issue, status, false, result, ""); finishErr != nil {
+			s.logger.Error("完成 Issue 处理失败: %v", finishErr)
+		}
+		s.logger.Error("Issue #%d 解析失败: %v", issue.Number, err)
+		return []IssueOutcome{{
+			Number:   issue.Number,
+			Error:    err.Error(),
+			Duration: time.Since(started),
+		}}
 	}
 
-	// 转换镜像名称
-	sourceImage, targetImage, err = s.imageTransformer.Transform(
-		originalImage,
-		registry,
-		namespace,
+	// 执行镜像同步
+	results := make([]*imageResult, 0, len(requests))
+	outcomes := make([]IssueOutcome, 0, len(requests))
+	success := true
+	var platforms []string
+	for n, request := range requests {
+		if platform != "" {
+			request.Platform = platform
+		}
+		stage := ""
+		if len(requests) > 1 {
+			stage = fmt.Sprintf("[%d/%d] ", n+1, len(requests))
+		}
+
+		imageStarted := time.Now()
+		result := &imageResult{request: request}
+		result.err = s.syncImage(ctx, issue, result, status, stage)
+		results = append(results, result)
+		if result.err != nil {
+			status.Stage(ctx, "%s❌ 同步失败", stage)
+		} else {
+			status.Stage(ctx, "%s✅ 同步完成", stage)
+		}
+
+		outcome := IssueOutcome{
+			Number:      issue.Number,
+			SourceImage: request.Image,
+			Success:     result.err == nil,
+			Duration:    time.Since(imageStarted),
+		}
+		if result.transformed != nil {
+			outcome.SourceImage, outcome.TargetImage = result.transformed.SourceImage, result.transformed.TargetImage
+		}
+		if result.err != nil {
+			outcome.Error = result.err.Error()
+			success = false
+			s.logger.Error("Issue #%d 镜像 %s 同步失败: %v", issue.Number, request.Image, result.err)
+		}
+		outcomes = append(outcomes, outcome)
+
+		if request.Platform != "" {
+			platforms = append(platforms, request.Platform)
+		}
+	}
+
+	// 生成结果报告，按标题请求的单个镜像保持原有格式
+	var report string
+	if structured {
+		report = s.generateBatchResult(results)
+	} else {
+		report = s.generateResult(results[0])
+	}
+
+	// 完成 Issue 处理，所有镜像都成功才标记为成功
+	if finishErr := s.issueProcessor.FinishIssue(ctx, issue, status, success, report, strings.Join(platforms, ",")); finishErr != nil {
+		s.logger.Error("完成 Issue 处理失败: %v", finishErr)
+	}
+
+	if success {
+		s.logger.Info("Issue #%d 处理完成", issue.Number)
+	}
+	return outcomes
+}
+
+// syncImage 同步 result 中请求的镜像，转换、构建和历史记录写入 result，各阶段写入状态评论，stage 为阶段描述的前缀
+// 源镜像所在仓库被重定向时从新仓库拉取和校验，目标镜像名称仍按原始请求计算
+// 之前的 Issue 已经同步过且上游未变化时不再构建，只添加额外标签；只有维护者的请求可以覆盖已指向其他镜像的标签
+func (s *DefaultSyncService) syncImage(ctx context.Context, issue *source.Request, result *imageResult, status *githubclient.StatusComment, stage string) error {
+	request := result.request
+	s.logger.Info("开始同步镜像: %s", request.Image)
+	status.Stage(ctx, "%s解析镜像 `%s`", stage, request.Image)
+
+	// 转换镜像名称，未匹配指定目标仓库的规则时使用通用配置
+	transformed, err := s.imageTransformer.TransformWithRepository(
+		request.Image,
+		docker.TargetFromConfig(s.config.GetEffectiveGenericConfig()),
+		request.Target,
 	)
+	result.transformed = transformed
 	if err != nil {
-		return "", "", fmt.Errorf("镜像名称转换失败: %w", err)
+		return fmt.Errorf("镜像名称转换失败: %w", err)
 	}
+	upstreamImage, targetImage := transformed.UpstreamImage, transformed.TargetImage
 
 	// 验证转换结果
-	if err := s.imageTransformer.ValidateTransformation(sourceImage, targetImage); err != nil {
-		return sourceImage, targetImage, fmt.Errorf("镜像名称验证失败: %w", err)
+	if err := s.imageTransformer.ValidateTransformation(transformed.SourceImage, targetImage); err != nil {
+		return fmt.Errorf("镜像名称验证失败: %w", err)
 	}
 
-	s.logger.Info("镜像名称转换完成: %s -> %s", sourceImage, targetImage)
+	s.logger.Info("镜像名称转换完成: %s -> %s", transformed.SourceImage, targetImage)
+
+	// 同步期间占用目标仓库，避免并发的其他源仓库推送到同一目标仓库
+	release, err := s.reserveMapping(transformed.SourceImage, targetImage)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// 上游未变化时复用之前的同步结果
+	s.checkHistory(ctx, issue.Number, result)
+	if result.duplicate != nil {
+		status.Stage(ctx, "%s上游镜像未变化，已在 #%d 同步过", stage, result.duplicate.Issue)
+		if err := s.tagImage(ctx, targetImage, request.Tags, issue.Maintainer); err != nil {
+			return fmt.Errorf("添加额外标签失败: %w", err)
+		}
+		return nil
+	}
+	if result.previous != nil {
+		status.Stage(ctx, "%s上游镜像已更新，上次同步见 #%d", stage, result.previous.Issue)
+	}
 
 	// 构建并推送镜像（内部会自动处理登录和架构检测）
-	if err := s.dockerBuilder.BuildAndPush(ctx, sourceImage, targetImage, platform); err != nil {
-		return sourceImage, targetImage, fmt.Errorf("Docker 构建推送失败: %w", err)
+	status.Stage(ctx, "%s复制 `%s` -> `%s`", stage, upstreamImage, targetImage)
+	buildCtx := docker.WithProgress(ctx, func(done, total int) {
+		status.Progress(ctx, "%s已复制 %d/%d 个 blob", stage, done, total)
+	})
+	build, err := s.dockerBuilder.BuildAndPush(buildCtx, upstreamImage, targetImage, request.Platform)
+	result.build = build
+	if err != nil {
+		return fmt.Errorf("Docker 构建推送失败: %w", err)
 	}
+	s.measureImage(ctx, result)
 
 	// 动态创建仓库处理器并设置镜像权限
+	status.Stage(ctx, "%s后处理和推送后校验", stage)
 	if err := s.processImageWithDynamicRegistry(targetImage); err != nil {
-		return sourceImage, targetImage, fmt.Errorf("设置镜像权限失败: %w", err)
+		return fmt.Errorf("设置镜像权限失败: %w", err)
+	}
+
+	// 校验目标镜像可拉取且与上游一致
+	if err := s.verifyImage(ctx, upstreamImage, targetImage, request.Platform); err != nil {
+		return fmt.Errorf("推送后校验失败: %w", err)
+	}
+
+	// 为目标镜像添加额外标签
+	if err := s.tagImage(ctx, targetImage, request.Tags, issue.Maintainer); err != nil {
+		return fmt.Errorf("添加额外标签失败: %w", err)
+	}
+
+	// 记录源仓库到目标仓库的映射
+	if err := s.recordMapping(ctx, transformed.SourceImage, targetImage); err != nil {
+		return err
 	}
 
 	s.logger.Info("镜像同步完成: %s", targetImage)
-	return sourceImage, targetImage, nil
+	return nil
+}
+
+// tagImage 将目标镜像的清单以额外标签推送到同一仓库
+// overwrite 为 false 时拒绝覆盖已指向其他清单的标签，避免改写其他请求同步的镜像
+func (s *DefaultSyncService) tagImage(ctx context.Context, targetImage string, tags []string, overwrite bool) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	ref, err := registry.ParseImageReference(targetImage)
+	if err != nil {
+		return err
+	}
+	digest := ""
+	if !overwrite {
+		if digest, err = s.manifestDigest(ctx, targetImage); err != nil {
+			return fmt.Errorf("获取目标镜像 %s 的清单摘要失败: %w", targetImage, err)
+		}
+	}
+	for _, tag := range tags {
+		if !overwrite {
+			tagged := ref
+			tagged.Reference = tag
+			existing, err := s.manifestDigest(ctx, tagged.String())
+			if err != nil && err != registry.ErrNotFound {
+				return fmt.Errorf("检查标签 %s 失败: %w", tagged, err)
+			}
+			if err == nil && existing != digest {
+				return fmt.Errorf("标签 %s 已指向其他镜像 %s，只有维护者可以覆盖", tagged, existing)
+			}
+		}
+		if err := s.registryClient.Tag(ctx, ref, tag); err != nil {
+			return err
+		}
+		s.logger.Info("已添加标签: %s -> %s", targetImage, tag)
+	}
+	return nil
 }
 
 // processImageWithDynamicRegistry 动态创建仓库处理器并处理镜像
@@ -180,6 +452,73 @@ func (s *DefaultSyncService) processImageWithDynamicRegistry(targetImage string)
 	return nil
 }
 
+// verifyImage 校验推送后的目标镜像与上游镜像一致
+func (s *DefaultSyncService) verifyImage(ctx context.Context, sourceImage, targetImage, platform string) error {
+	if !s.config.Verify.Enabled || s.verifier == nil {
+		s.logger.Debug("跳过推送后校验")
+		return nil
+	}
+
+	platforms := s.config.Platforms
+	if platform != "" {
+		platforms = platform
+	}
+
+	opts := registry.VerifyOptions{
+		Platforms:     strings.Split(platforms, ","),
+		CompareConfig: s.config.Verify.CompareConfig,
+	}
+	switch s.config.Verify.Anonymous {
+	case "always":
+		opts.Anonymous = true
+	case "never":
+		opts.Anonymous = false
+	default:
+		opts.Anonymous = s.registryFactory.ExpectsPublicAccess(targetImage, s.extractRegistryURL(targetImage))
+	}
+
+	return s.verifier.Verify(ctx, sourceImage, targetImage, opts)
+}
+
+// loadMappingIndex 读取最新的映射索引，未启用映射索引时跳过
+func (s *DefaultSyncService) loadMappingIndex(ctx context.Context) error {
+	if s.mappingIndex == nil {
+		return nil
+	}
+
+	if err := s.mappingIndex.Load(ctx); err != nil {
+		return err
+	}
+	s.logger.Debug("已加载映射索引，共 %d 个目标仓库", len(s.mappingIndex.Targets()))
+	return nil
+}
+
+// reserveMapping 在推送前占用目标仓库，返回的函数用于释放，未启用映射索引时不做任何事
+func (s *DefaultSyncService) reserveMapping(sourceImage, targetImage string) (func(), error) {
+	if s.mappingIndex == nil {
+		return func() {}, nil
+	}
+	return s.mappingIndex.Reserve(sourceImage, targetImage)
+}
+
+// recordMapping 记录同步成功的源仓库和目标仓库
+// 其他运行期间已有源仓库记录了同一目标仓库时返回 CollisionError，其他记录失败只输出警告
+func (s *DefaultSyncService) recordMapping(ctx context.Context, sourceImage, targetImage string) error {
+	if s.mappingIndex == nil {
+		return nil
+	}
+
+	err := s.mappingIndex.Record(ctx, sourceImage, targetImage)
+	var collision *mapping.CollisionError
+	if stderrors.As(err, &collision) {
+		return fmt.Errorf("镜像已推送但目标仓库冲突: %w", err)
+	}
+	if err != nil {
+		s.logger.Warn("记录镜像映射失败: %v", err)
+	}
+	return nil
+}
+
 // extractRegistryURL 从镜像名称中提取仓库URL
 func (s *DefaultSyncService) extractRegistryURL(imageName string) string {
 	// 镜像名称格式: registry.domain.com/namespace/image:tag
@@ -192,38 +531,68 @@ func (s *DefaultSyncService) extractRegistryURL(imageName string) string {
 	return "docker.io"
 }
 
-// generateResult 生成结果报告
-func (s *DefaultSyncService) generateResult(sourceImage, targetImage, platform string, success bool, err error) string {
+// generateResult 生成单个镜像的结果报告
+func (s *DefaultSyncService) generateResult(image *imageResult) string {
+	transformed, build, err := image.transformed, image.build, image.err
+	success := err == nil
 	result := ResultData{
-		Success:          success,
-		SourceImage:      sourceImage,
-		TargetImage:      targetImage,
-		Platform:         platform,
-		GitHubUser:       s.config.GitHub.User,
-		GitHubRepo:       s.config.GitHub.Repo,
-		GitHubRunID:      s.config.GitHub.RunID,
-		ErrorMessage:     "",
-		ArchitectureInfo: s.dockerBuilder.GetLastArchitectureInfo(), // 获取架构信息
+		Success:      success,
+		Platform:     image.request.Platform,
+		GitHubUser:   s.config.GitHub.User,
+		GitHubRepo:   s.config.GitHub.Repo,
+		GitHubRunID:  s.config.GitHub.RunID,
+		ErrorMessage: "",
+	}
+	if build != nil {
+		result.ArchitectureInfo = build.ArchitectureInfo
+	}
+	if transformed != nil {
+		result.SourceImage = transformed.SourceImage
+		result.TargetImage = transformed.TargetImage
+		if transformed.Redirected() {
+			result.UpstreamImage = transformed.UpstreamImage
+		}
+	}
+	if success {
+		result.Record = s.syncRecord(image)
+		if image.duplicate != nil {
+			result.DuplicateIssue = image.duplicate.Issue
+		} else if image.previous != nil {
+			result.PreviousIssue = image.previous.Issue
+		}
 	}
 
 	if !success && err != nil {
-		if appErr, ok := err.(*errors.AppError); ok {
-			result.ErrorMessage = errors.FormatUserError(appErr, s.config.GitHub.User)
-			// 提供详细的错误信息
-			result.ErrorDetails = s.formatErrorDetails(appErr)
-		} else {
-			result.ErrorMessage = fmt.Sprintf("操作失败: %v", err)
-			result.ErrorDetails = err.Error()
-		}
+		result.ErrorMessage, result.ErrorDetails, result.VerificationDiff = s.describeError(err)
 	}
 
 	return s.renderTemplate(result)
 }
 
+// describeError 返回面向用户的错误原因、详细错误信息和推送后校验差异
+func (s *DefaultSyncService) describeError(err error) (message, details, diff string) {
+	if appErr, ok := err.(*errors.AppError); ok {
+		message = errors.FormatUserError(appErr, s.config.GitHub.User)
+		// 提供详细的错误信息
+		details = s.formatErrorDetails(appErr)
+	} else {
+		message = fmt.Sprintf("操作失败: %v", err)
+		details = err.Error()
+	}
+
+	var verifyErr *registry.VerificationError
+	if stderrors.As(err, &verifyErr) {
+		message = fmt.Sprintf("@%s 目标镜像与上游镜像不一致", s.config.GitHub.User)
+		diff = verifyErr.Diff()
+	}
+	return message, details, diff
+}
+
 // ResultData 结果数据结构
 type ResultData struct {
 	Success          bool
 	SourceImage      string
+	UpstreamImage    string // 重定向后实际拉取的上游镜像，未重定向时为空
 	TargetImage      string
 	Platform         string
 	GitHubUser       string
@@ -232,6 +601,10 @@ type ResultData struct {
 	ErrorMessage     string
 	ErrorDetails     string // 详细错误信息
 	ArchitectureInfo string // 架构信息
+	VerificationDiff string // 推送后校验差异
+	DuplicateIssue   int    // 上游未变化、直接复用同步结果的历史 Issue
+	PreviousIssue    int    // 上游已更新、重新同步前的历史 Issue
+	Record           string // 隐藏的同步记录标记
 }
 
 // renderTemplate 渲染模板
@@ -254,17 +627,36 @@ docker tag {{ .TargetImage }} {{ .SourceImage }}
 
 docker images | grep $(echo {{ .SourceImage }} | awk -F':' '{print $1}')
 ` + "```" + `
+{{ if .DuplicateIssue }}
+> ♻️ 该镜像已在 #{{ .DuplicateIssue }} 同步过且上游镜像未变化，本次直接复用之前的同步结果
+{{ else if .PreviousIssue }}
+> 🔄 该镜像曾在 #{{ .PreviousIssue }} 同步过，上游镜像已更新，本次重新同步
+{{ end }}
+{{ if .UpstreamImage }}
+> ℹ️ 源镜像所在的仓库已停止更新，本次从 ` + "`{{ .UpstreamImage }}`" + ` 拉取，目标镜像名称保持不变
+{{ end }}
 {{ if .ArchitectureInfo }}
 
 {{ .ArchitectureInfo }}
 {{ end }}
 
 ---
-📋 **构建详情**: [查看构建日志](https://github.com/{{ .GitHubUser }}/{{ .GitHubRepo }}/actions/runs/{{ .GitHubRunID }})
+{{ if .GitHubRunID }}📋 **构建详情**: [查看构建日志](https://github.com/{{ .GitHubUser }}/{{ .GitHubRepo }}/actions/runs/{{ .GitHubRunID }}){{ end }}
+{{ .Record }}
 {{ else }}
 **❌ 转换失败**
 
 {{ if .ErrorMessage }}**错误原因**: {{ .ErrorMessage }}{{ end }}
+{{ if .UpstreamImage }}
+> ℹ️ 源镜像所在的仓库已停止更新，已重定向到 ` + "`{{ .UpstreamImage }}`" + `
+{{ end }}
+
+{{ if .VerificationDiff }}
+**校验差异**（上游 {{ if .UpstreamImage }}{{ .UpstreamImage }}{{ else }}{{ .SourceImage }}{{ end }} / 目标 {{ .TargetImage }}）:
+` + "```diff" + `
+{{ .VerificationDiff }}
+` + "```" + `
+{{ end }}
 
 {{ if .ErrorDetails }}
 **详细错误信息**:
@@ -283,8 +675,8 @@ docker images | grep $(echo {{ .SourceImage }} | awk -F':' '{print $1}')
 1. 检查镜像名称是否正确
 2. 确认上游镜像是否存在
 3. 查看详细的构建日志
-
-📋 **构建详情**: [查看构建日志](https://github.com/{{ .GitHubUser }}/{{ .GitHubRepo }}/actions/runs/{{ .GitHubRunID }})
+{{ if .GitHubRunID }}
+📋 **构建详情**: [查看构建日志](https://github.com/{{ .GitHubUser }}/{{ .GitHubRepo }}/actions/runs/{{ .GitHubRunID }}){{ end }}
 {{ end }}`
 
 	funcMap := template.FuncMap{
@@ -336,6 +728,10 @@ func (s *DefaultSyncService) formatErrorDetails(appErr *errors.AppError) string
 func (s *DefaultSyncService) Cleanup() error {
 	s.logger.Debug("清理服务资源")
 
+	if s.dockerBuilder == nil {
+		return nil
+	}
+
 	if err := s.dockerBuilder.Cleanup(); err != nil {
 		s.logger.Warn("清理 Docker 构建器失败: %v", err)
 		return err
@@ -0,0 +1,103 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiTimeout 单个 API 请求的超时时间
+const apiTimeout = 30 * time.Second
+
+// APIError GitLab 或 Gitea API 返回的错误响应
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string // 响应内容，通常为 JSON 格式的错误说明
+}
+
+// Error 实现 error 接口
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s 返回 %d", e.Method, e.Path, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s 返回 %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// apiClient GitLab 和 Gitea REST API 的 JSON 客户端
+type apiClient struct {
+	baseURL string // 以 /api/v4 或 /api/v1 结尾，不带末尾的 /
+	header  http.Header
+	client  *http.Client
+}
+
+// newAPIClient 创建 API 客户端，header 为每个请求附带的认证头
+func newAPIClient(instanceURL, apiPath string, header http.Header) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimSuffix(instanceURL, "/") + apiPath,
+		header:  header,
+		client:  &http.Client{Timeout: apiTimeout},
+	}
+}
+
+// do 发送请求并返回响应头，body 不为空时以 JSON 发送，out 不为空时解析 JSON 响应
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (http.Header, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("解析 %s %s 的响应失败: %w", method, path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// repoPath 将 owner/repo 形式的仓库路径逐段转义后拼接
+func repoPath(project string) string {
+	parts := strings.Split(strings.Trim(project, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,383 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sync-image/internal/config"
+	"sync-image/pkg/errors"
+	"sync-image/pkg/logger"
+)
+
+// giteaPageSize Gitea 列表接口每页的数量，不超过实例默认的 MAX_RESPONSE_ITEMS
+const giteaPageSize = 50
+
+// giteaLabelColor 自动创建标签时使用的颜色
+const giteaLabelColor = "#ededed"
+
+// giteaUser Gitea 用户
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+// giteaLabel Gitea 标签
+type giteaLabel struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// giteaIssue Gitea Issue 中需要的字段
+type giteaIssue struct {
+	Number      int          `json:"number"`
+	Title       string       `json:"title"`
+	Body        string       `json:"body"`
+	User        giteaUser    `json:"user"`
+	State       string       `json:"state"`
+	Labels      []giteaLabel `json:"labels"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	ClosedAt    *time.Time   `json:"closed_at"`
+	HTMLURL     string       `json:"html_url"`
+	PullRequest *struct{}    `json:"pull_request"`
+}
+
+// giteaComment Gitea Issue 评论
+type giteaComment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	User      giteaUser `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GiteaSource 从 Gitea 或 Forgejo 仓库的 Issue 读取搬运请求
+// Gitea 按 ID 添加标签，标签名称到 ID 的映射在第一次使用时读取，不存在的标签自动创建
+type GiteaSource struct {
+	api    *apiClient
+	repo   string // 转义后的 owner/repo
+	logger logger.Logger
+
+	mu     sync.Mutex
+	labels map[string]int64 // 仓库标签名称到 ID，为 nil 表示尚未读取
+}
+
+// NewGiteaSource 创建 Gitea 请求来源，cfg.URL 为实例地址，cfg.Project 为 owner/repo
+func NewGiteaSource(cfg *config.SourceConfig, log logger.Logger) *GiteaSource {
+	header := http.Header{}
+	header.Set("Authorization", "token "+cfg.Token)
+
+	return &GiteaSource{
+		api:    newAPIClient(cfg.URL, "/api/v1", header),
+		repo:   repoPath(cfg.Project),
+		logger: log,
+	}
+}
+
+// Type 实现 RequestSource
+func (g *GiteaSource) Type() string {
+	return config.SourceGitea
+}
+
+// Identity 实现 RequestSource
+func (g *GiteaSource) Identity(ctx context.Context) (string, error) {
+	var user giteaUser
+	if _, err := g.api.do(ctx, http.MethodGet, "/user", nil, nil, &user); err != nil {
+		return "", errors.NewSourceError("获取当前用户失败", err)
+	}
+	return user.Login, nil
+}
+
+// PendingRequests 实现 RequestSource
+func (g *GiteaSource) PendingRequests(ctx context.Context) ([]*Request, error) {
+	g.logger.Debug("获取待处理的 Issues")
+
+	issues, err := g.listIssues(ctx, url.Values{
+		"state":  {"open"},
+		"type":   {"issues"},
+		"labels": {LabelRequest},
+	}, 0)
+	if err != nil {
+		return nil, errors.NewSourceError("获取 Issues 失败", err)
+	}
+
+	// 接口按创建时间从新到旧返回，先处理较早的请求
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].CreatedAt.Before(issues[j].CreatedAt)
+	})
+
+	requests := toGiteaRequests(issues)
+	g.logger.Info("找到 %d 个待处理的 Issues", len(requests))
+	return requests, nil
+}
+
+// GetRequest 实现 RequestSource
+func (g *GiteaSource) GetRequest(ctx context.Context, number int) (*Request, error) {
+	var issue giteaIssue
+	if _, err := g.api.do(ctx, http.MethodGet, g.issuePath(number), nil, nil, &issue); err != nil {
+		return nil, errors.NewSourceError(fmt.Sprintf("获取 Issue #%d 失败", number), err).
+			WithContext("issue_number", number)
+	}
+	return issue.toRequest(), nil
+}
+
+// RequesterRequests 实现 RequestSource
+func (g *GiteaSource) RequesterRequests(ctx context.Context, user string, since time.Time) ([]*Request, error) {
+	issues, err := g.listIssues(ctx, url.Values{
+		"state":      {"all"},
+		"type":       {"issues"},
+		"labels":     {LabelRequest},
+		"created_by": {user},
+		"since":      {since.UTC().Format(time.RFC3339)},
+	}, 0)
+	if err != nil {
+		return nil, errors.NewSourceError(fmt.Sprintf("获取用户 %s 的 Issues 失败", user), err)
+	}
+	return toGiteaRequests(issues), nil
+}
+
+// SearchClosed 实现 RequestSource，搜索依赖实例启用的 Issue 索引
+func (g *GiteaSource) SearchClosed(ctx context.Context, label, text string, limit int) ([]*Request, error) {
+	issues, err := g.listIssues(ctx, url.Values{
+		"state":  {"closed"},
+		"type":   {"issues"},
+		"labels": {label},
+		"q":      {text},
+	}, limit)
+	if err != nil {
+		return nil, errors.NewSourceError("搜索历史同步记录失败", err)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].UpdatedAt.After(issues[j].UpdatedAt)
+	})
+	return toGiteaRequests(issues), nil
+}
+
+// ListComments 实现 RequestSource
+func (g *GiteaSource) ListComments(ctx context.Context, number int) ([]*Comment, error) {
+	var comments []giteaComment
+	if _, err := g.api.do(ctx, http.MethodGet, g.issuePath(number)+"/comments", nil, nil, &comments); err != nil {
+		return nil, errors.NewSourceError(fmt.Sprintf("获取 Issue #%d 的评论失败", number), err).
+			WithContext("issue_number", number)
+	}
+
+	result := make([]*Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, &Comment{ID: comment.ID, Body: comment.Body, Author: comment.User.Login, CreatedAt: comment.CreatedAt})
+	}
+	return result, nil
+}
+
+// CreateComment 实现 RequestSource
+func (g *GiteaSource) CreateComment(ctx context.Context, request *Request, body string) (int64, error) {
+	g.logger.Debug("为 Issue #%d 添加评论", request.Number)
+
+	var created giteaComment
+	if _, err := g.api.do(ctx, http.MethodPost, g.issuePath(request.Number)+"/comments", nil,
+		map[string]string{"body": body}, &created); err != nil {
+		return 0, errors.NewSourceError(fmt.Sprintf("为 Issue #%d 添加评论失败", request.Number), err).
+			WithContext("issue_number", request.Number)
+	}
+
+	g.logger.Info("成功为 Issue #%d 添加评论", request.Number)
+	return created.ID, nil
+}
+
+// EditComment 实现 RequestSource
+func (g *GiteaSource) EditComment(ctx context.Context, request *Request, commentID int64, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/comments/%d", g.repo, commentID)
+	if _, err := g.api.do(ctx, http.MethodPatch, path, nil, map[string]string{"body": body}, nil); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("修改 Issue #%d 的评论失败", request.Number), err).
+			WithContext("issue_number", request.Number).WithContext("comment_id", commentID)
+	}
+	return nil
+}
+
+// AddReaction 实现 RequestSource
+func (g *GiteaSource) AddReaction(ctx context.Context, request *Request, commentID int64, content string) error {
+	path := fmt.Sprintf("/repos/%s/issues/comments/%d/reactions", g.repo, commentID)
+	if _, err := g.api.do(ctx, http.MethodPost, path, nil, map[string]string{"content": content}, nil); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("为 Issue #%d 的评论添加回应失败", request.Number), err).
+			WithContext("comment_id", commentID)
+	}
+	return nil
+}
+
+// AddLabels 实现 RequestSource
+func (g *GiteaSource) AddLabels(ctx context.Context, request *Request, labels []string) error {
+	g.logger.Debug("为 Issue #%d 添加标签: %v", request.Number, labels)
+
+	ids, err := g.labelIDs(ctx, labels)
+	if err == nil {
+		_, err = g.api.do(ctx, http.MethodPost, g.issuePath(request.Number)+"/labels", nil,
+			map[string][]int64{"labels": ids}, nil)
+	}
+	if err != nil {
+		return errors.NewSourceError(fmt.Sprintf("为 Issue #%d 添加标签失败", request.Number), err).
+			WithContext("issue_number", request.Number).WithContext("labels", labels)
+	}
+
+	g.logger.Info("成功为 Issue #%d 添加标签: %v", request.Number, labels)
+	return nil
+}
+
+// RemoveLabel 实现 RequestSource，仓库中不存在的标签直接忽略
+func (g *GiteaSource) RemoveLabel(ctx context.Context, request *Request, label string) error {
+	g.logger.Debug("移除 Issue #%d 的标签: %s", request.Number, label)
+
+	g.mu.Lock()
+	err := g.loadLabels(ctx)
+	id, ok := g.labels[label]
+	g.mu.Unlock()
+	if err == nil && !ok {
+		return nil
+	}
+	if err == nil {
+		_, err = g.api.do(ctx, http.MethodDelete, fmt.Sprintf("%s/labels/%d", g.issuePath(request.Number), id), nil, nil, nil)
+	}
+	if err != nil {
+		return errors.NewSourceError(fmt.Sprintf("移除 Issue #%d 的标签失败", request.Number), err).
+			WithContext("issue_number", request.Number).WithContext("label", label)
+	}
+	return nil
+}
+
+// CloseRequest 实现 RequestSource
+func (g *GiteaSource) CloseRequest(ctx context.Context, request *Request) error {
+	g.logger.Debug("关闭 Issue #%d", request.Number)
+
+	if _, err := g.api.do(ctx, http.MethodPatch, g.issuePath(request.Number), nil,
+		map[string]string{"state": StateClosed}, nil); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("关闭 Issue #%d 失败", request.Number), err).
+			WithContext("issue_number", request.Number)
+	}
+
+	g.logger.Info("成功关闭 Issue #%d", request.Number)
+	return nil
+}
+
+// listIssues 按条件分页列出 Issues，limit 大于 0 时最多返回 limit 个
+func (g *GiteaSource) listIssues(ctx context.Context, query url.Values, limit int) ([]giteaIssue, error) {
+	pageSize := giteaPageSize
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	query.Set("limit", strconv.Itoa(pageSize))
+
+	var issues []giteaIssue
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var batch []giteaIssue
+		if _, err := g.api.do(ctx, http.MethodGet, "/repos/"+g.repo+"/issues", query, nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, issue := range batch {
+			if issue.PullRequest == nil {
+				issues = append(issues, issue)
+			}
+		}
+
+		if len(batch) < pageSize || (limit > 0 && len(issues) >= limit) {
+			break
+		}
+	}
+
+	if limit > 0 && len(issues) > limit {
+		issues = issues[:limit]
+	}
+	return issues, nil
+}
+
+// labelIDs 返回标签名称对应的 ID，仓库中不存在的标签自动创建
+func (g *GiteaSource) labelIDs(ctx context.Context, names []string) ([]int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.loadLabels(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		id, ok := g.labels[name]
+		if !ok {
+			var created giteaLabel
+			if _, err := g.api.do(ctx, http.MethodPost, "/repos/"+g.repo+"/labels", nil,
+				map[string]string{"name": name, "color": giteaLabelColor}, &created); err != nil {
+				return nil, fmt.Errorf("创建标签 %s 失败: %w", name, err)
+			}
+			id = created.ID
+			g.labels[name] = id
+			g.logger.Info("已在仓库中创建标签: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// loadLabels 读取仓库的所有标签，调用方需持有 g.mu
+func (g *GiteaSource) loadLabels(ctx context.Context) error {
+	if g.labels != nil {
+		return nil
+	}
+
+	labels := make(map[string]int64)
+	for page := 1; ; page++ {
+		var batch []giteaLabel
+		query := url.Values{"page": {strconv.Itoa(page)}, "limit": {strconv.Itoa(giteaPageSize)}}
+		if _, err := g.api.do(ctx, http.MethodGet, "/repos/"+g.repo+"/labels", query, nil, &batch); err != nil {
+			return fmt.Errorf("获取仓库标签失败: %w", err)
+		}
+		for _, label := range batch {
+			labels[label.Name] = label.ID
+		}
+		if len(batch) < giteaPageSize {
+			break
+		}
+	}
+
+	g.labels = labels
+	return nil
+}
+
+// issuePath 返回 Issue 的 API 路径
+func (g *GiteaSource) issuePath(number int) string {
+	return fmt.Sprintf("/repos/%s/issues/%d", g.repo, number)
+}
+
+// toRequest 将 Gitea Issue 转换为搬运请求
+func (i *giteaIssue) toRequest() *Request {
+	request := &Request{
+		Number:      i.Number,
+		Title:       i.Title,
+		Body:        i.Body,
+		Author:      i.User.Login,
+		State:       strings.ToLower(i.State),
+		CreatedAt:   i.CreatedAt,
+		URL:         i.HTMLURL,
+		PullRequest: i.PullRequest != nil,
+	}
+	if i.ClosedAt != nil {
+		request.ClosedAt = *i.ClosedAt
+	}
+	for _, label := range i.Labels {
+		request.Labels = append(request.Labels, label.Name)
+	}
+	return request
+}
+
+// toGiteaRequests 批量转换 Gitea Issues
+func toGiteaRequests(issues []giteaIssue) []*Request {
+	requests := make([]*Request, 0, len(issues))
+	for i := range issues {
+		requests = append(requests, issues[i].toRequest())
+	}
+	return requests
+}
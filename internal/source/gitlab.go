@@ -0,0 +1,314 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sync-image/internal/config"
+	"sync-image/pkg/errors"
+	"sync-image/pkg/logger"
+)
+
+// gitlabPageSize GitLab 列表接口每页的数量
+const gitlabPageSize = 100
+
+// gitlabReactions 评论回应到 GitLab 表情名称
+var gitlabReactions = map[string]string{
+	"+1": "thumbsup",
+	"-1": "thumbsdown",
+}
+
+// gitlabUser GitLab 用户
+type gitlabUser struct {
+	Username string `json:"username"`
+	Bot      bool   `json:"bot"`
+}
+
+// gitlabIssue GitLab Issue 中需要的字段
+type gitlabIssue struct {
+	IID         int        `json:"iid"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Author      gitlabUser `json:"author"`
+	State       string     `json:"state"` // opened 或 closed
+	Labels      []string   `json:"labels"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	WebURL      string     `json:"web_url"`
+}
+
+// gitlabNote GitLab Issue 评论
+type gitlabNote struct {
+	ID        int64      `json:"id"`
+	Body      string     `json:"body"`
+	Author    gitlabUser `json:"author"`
+	System    bool       `json:"system"` // 标签变更等系统记录
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// GitLabSource 从 GitLab 项目的 Issue 读取搬运请求
+// GitLab 的 Issue 编号为项目内的 iid，添加不存在的标签时由 GitLab 自动创建
+type GitLabSource struct {
+	api     *apiClient
+	project string // URL 编码后的项目路径
+	logger  logger.Logger
+}
+
+// NewGitLabSource 创建 GitLab 请求来源，cfg.URL 为实例地址，cfg.Project 为项目路径
+func NewGitLabSource(cfg *config.SourceConfig, log logger.Logger) *GitLabSource {
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", cfg.Token)
+
+	return &GitLabSource{
+		api:     newAPIClient(cfg.URL, "/api/v4", header),
+		project: url.PathEscape(strings.Trim(cfg.Project, "/")),
+		logger:  log,
+	}
+}
+
+// Type 实现 RequestSource
+func (g *GitLabSource) Type() string {
+	return config.SourceGitLab
+}
+
+// Identity 实现 RequestSource
+func (g *GitLabSource) Identity(ctx context.Context) (string, error) {
+	var user gitlabUser
+	if _, err := g.api.do(ctx, http.MethodGet, "/user", nil, nil, &user); err != nil {
+		return "", errors.NewSourceError("获取当前用户失败", err)
+	}
+	return user.Username, nil
+}
+
+// PendingRequests 实现 RequestSource
+func (g *GitLabSource) PendingRequests(ctx context.Context) ([]*Request, error) {
+	g.logger.Debug("获取待处理的 Issues")
+
+	issues, err := g.listIssues(ctx, url.Values{
+		"state":    {"opened"},
+		"labels":   {LabelRequest},
+		"order_by": {"created_at"},
+		"sort":     {"asc"}, // 先处理较早的请求，避免后来的请求一直被优先处理
+	}, 0)
+	if err != nil {
+		return nil, errors.NewSourceError("获取 Issues 失败", err)
+	}
+
+	g.logger.Info("找到 %d 个待处理的 Issues", len(issues))
+	return issues, nil
+}
+
+// GetRequest 实现 RequestSource
+func (g *GitLabSource) GetRequest(ctx context.Context, number int) (*Request, error) {
+	var issue gitlabIssue
+	if _, err := g.api.do(ctx, http.MethodGet, g.issuePath(number), nil, nil, &issue); err != nil {
+		return nil, errors.NewSourceError(fmt.Sprintf("获取 Issue #%d 失败", number), err).
+			WithContext("issue_number", number)
+	}
+	return issue.toRequest(), nil
+}
+
+// RequesterRequests 实现 RequestSource
+func (g *GitLabSource) RequesterRequests(ctx context.Context, user string, since time.Time) ([]*Request, error) {
+	requests, err := g.listIssues(ctx, url.Values{
+		"labels":          {LabelRequest},
+		"author_username": {user},
+		"updated_after":   {since.UTC().Format(time.RFC3339)},
+	}, 0)
+	if err != nil {
+		return nil, errors.NewSourceError(fmt.Sprintf("获取用户 %s 的 Issues 失败", user), err)
+	}
+	return requests, nil
+}
+
+// SearchClosed 实现 RequestSource，GitLab 只搜索标题和内容，不搜索评论
+func (g *GitLabSource) SearchClosed(ctx context.Context, label, text string, limit int) ([]*Request, error) {
+	requests, err := g.listIssues(ctx, url.Values{
+		"state":    {"closed"},
+		"labels":   {label},
+		"search":   {text},
+		"order_by": {"updated_at"},
+		"sort":     {"desc"},
+	}, limit)
+	if err != nil {
+		return nil, errors.NewSourceError("搜索历史同步记录失败", err)
+	}
+	return requests, nil
+}
+
+// ListComments 实现 RequestSource，不包括系统记录
+func (g *GitLabSource) ListComments(ctx context.Context, number int) ([]*Comment, error) {
+	query := url.Values{
+		"order_by": {"created_at"},
+		"sort":     {"asc"},
+		"per_page": {strconv.Itoa(gitlabPageSize)},
+	}
+
+	var comments []*Comment
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+
+		var notes []gitlabNote
+		header, err := g.api.do(ctx, http.MethodGet, g.issuePath(number)+"/notes", query, nil, &notes)
+		if err != nil {
+			return nil, errors.NewSourceError(fmt.Sprintf("获取 Issue #%d 的评论失败", number), err).
+				WithContext("issue_number", number)
+		}
+		for _, note := range notes {
+			if !note.System {
+				comments = append(comments, &Comment{
+					ID:        note.ID,
+					Body:      note.Body,
+					Author:    note.Author.Username,
+					Bot:       note.Author.Bot,
+					CreatedAt: note.CreatedAt,
+				})
+			}
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return comments, nil
+}
+
+// CreateComment 实现 RequestSource
+func (g *GitLabSource) CreateComment(ctx context.Context, request *Request, body string) (int64, error) {
+	g.logger.Debug("为 Issue #%d 添加评论", request.Number)
+
+	var created gitlabNote
+	if _, err := g.api.do(ctx, http.MethodPost, g.issuePath(request.Number)+"/notes", nil,
+		map[string]string{"body": body}, &created); err != nil {
+		return 0, errors.NewSourceError(fmt.Sprintf("为 Issue #%d 添加评论失败", request.Number), err).
+			WithContext("issue_number", request.Number)
+	}
+
+	g.logger.Info("成功为 Issue #%d 添加评论", request.Number)
+	return created.ID, nil
+}
+
+// EditComment 实现 RequestSource
+func (g *GitLabSource) EditComment(ctx context.Context, request *Request, commentID int64, body string) error {
+	path := fmt.Sprintf("%s/notes/%d", g.issuePath(request.Number), commentID)
+	if _, err := g.api.do(ctx, http.MethodPut, path, nil, map[string]string{"body": body}, nil); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("修改 Issue #%d 的评论失败", request.Number), err).
+			WithContext("issue_number", request.Number).WithContext("comment_id", commentID)
+	}
+	return nil
+}
+
+// AddReaction 实现 RequestSource，回应以表情（award emoji）添加到评论
+func (g *GitLabSource) AddReaction(ctx context.Context, request *Request, commentID int64, content string) error {
+	name, ok := gitlabReactions[content]
+	if !ok {
+		name = content
+	}
+
+	path := fmt.Sprintf("%s/notes/%d/award_emoji", g.issuePath(request.Number), commentID)
+	if _, err := g.api.do(ctx, http.MethodPost, path, nil, map[string]string{"name": name}, nil); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("为 Issue #%d 的评论添加回应失败", request.Number), err).
+			WithContext("comment_id", commentID)
+	}
+	return nil
+}
+
+// AddLabels 实现 RequestSource
+func (g *GitLabSource) AddLabels(ctx context.Context, request *Request, labels []string) error {
+	g.logger.Debug("为 Issue #%d 添加标签: %v", request.Number, labels)
+
+	if err := g.updateIssue(ctx, request, map[string]string{"add_labels": strings.Join(labels, ",")}); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("为 Issue #%d 添加标签失败", request.Number), err).
+			WithContext("issue_number", request.Number).WithContext("labels", labels)
+	}
+
+	g.logger.Info("成功为 Issue #%d 添加标签: %v", request.Number, labels)
+	return nil
+}
+
+// RemoveLabel 实现 RequestSource
+func (g *GitLabSource) RemoveLabel(ctx context.Context, request *Request, label string) error {
+	g.logger.Debug("移除 Issue #%d 的标签: %s", request.Number, label)
+
+	if err := g.updateIssue(ctx, request, map[string]string{"remove_labels": label}); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("移除 Issue #%d 的标签失败", request.Number), err).
+			WithContext("issue_number", request.Number).WithContext("label", label)
+	}
+	return nil
+}
+
+// CloseRequest 实现 RequestSource
+func (g *GitLabSource) CloseRequest(ctx context.Context, request *Request) error {
+	g.logger.Debug("关闭 Issue #%d", request.Number)
+
+	if err := g.updateIssue(ctx, request, map[string]string{"state_event": "close"}); err != nil {
+		return errors.NewSourceError(fmt.Sprintf("关闭 Issue #%d 失败", request.Number), err).
+			WithContext("issue_number", request.Number)
+	}
+
+	g.logger.Info("成功关闭 Issue #%d", request.Number)
+	return nil
+}
+
+// updateIssue 修改 Issue 的标签或状态
+func (g *GitLabSource) updateIssue(ctx context.Context, request *Request, changes map[string]string) error {
+	_, err := g.api.do(ctx, http.MethodPut, g.issuePath(request.Number), nil, changes, nil)
+	return err
+}
+
+// listIssues 按条件分页列出 Issues，limit 大于 0 时最多返回 limit 个
+func (g *GitLabSource) listIssues(ctx context.Context, query url.Values, limit int) ([]*Request, error) {
+	pageSize := gitlabPageSize
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	query.Set("per_page", strconv.Itoa(pageSize))
+
+	var requests []*Request
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+
+		var issues []gitlabIssue
+		header, err := g.api.do(ctx, http.MethodGet, "/projects/"+g.project+"/issues", query, nil, &issues)
+		if err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			requests = append(requests, issues[i].toRequest())
+		}
+
+		if limit > 0 && len(requests) >= limit {
+			return requests[:limit], nil
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return requests, nil
+}
+
+// issuePath 返回 Issue 的 API 路径
+func (g *GitLabSource) issuePath(number int) string {
+	return fmt.Sprintf("/projects/%s/issues/%d", g.project, number)
+}
+
+// toRequest 将 GitLab Issue 转换为搬运请求，opened 状态转换为 open
+func (i *gitlabIssue) toRequest() *Request {
+	request := &Request{
+		Number:    i.IID,
+		Title:     i.Title,
+		Body:      i.Description,
+		Author:    i.Author.Username,
+		State:     StateClosed,
+		Labels:    i.Labels,
+		CreatedAt: i.CreatedAt,
+		URL:       i.WebURL,
+	}
+	if i.State == "opened" {
+		request.State = StateOpen
+	}
+	if i.ClosedAt != nil {
+		request.ClosedAt = *i.ClosedAt
+	}
+	return request
+}
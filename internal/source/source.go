@@ -0,0 +1,99 @@
+// Package source 定义搬运请求的来源
+//
+// 搬运请求是代码托管平台仓库中带 porter 标签的 Issue。RequestSource 屏蔽了 GitHub、
+// GitLab 和 Gitea/Forgejo 的接口差异，Issue 的解析、状态评论、请求者限制和斜杠命令
+// 只依赖本包定义的 Request 和 Comment。GitHub 的实现在 internal/github 中。
+package source
+
+import (
+	"context"
+	"time"
+)
+
+// Issue 状态
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+)
+
+// LabelRequest 搬运请求的标签
+const LabelRequest = "porter"
+
+// Request 搬运请求，即仓库中的一个 Issue
+type Request struct {
+	Number      int    // 仓库内的 Issue 编号，GitLab 为 iid
+	Title       string // 标题
+	Body        string // 内容
+	Author      string // 作者的用户名
+	Maintainer  bool   // 作者是否为仓库维护者，只有 GitHub 提供
+	State       string // open 或 closed
+	Labels      []string
+	CreatedAt   time.Time
+	ClosedAt    time.Time // 未关闭时为零值
+	URL         string    // Issue 的网页地址
+	PullRequest bool      // 是否为 Pull Request，GitHub 和 Gitea 的 Issue 接口也会返回 Pull Request
+}
+
+// HasLabel 检查请求是否带有指定标签
+func (r *Request) HasLabel(name string) bool {
+	for _, label := range r.Labels {
+		if label == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen 请求是否尚未关闭
+func (r *Request) IsOpen() bool {
+	return r.State == StateOpen
+}
+
+// Comment Issue 评论
+type Comment struct {
+	ID         int64
+	Body       string
+	Author     string // 评论者的用户名
+	Maintainer bool   // 评论者是否为仓库维护者，只有 GitHub 提供
+	Bot        bool   // 是否为机器人发布的评论
+	CreatedAt  time.Time
+}
+
+// CommentEvent Issue 评论事件，用于处理斜杠命令
+type CommentEvent struct {
+	Action  string // created、edited 或 deleted
+	Request *Request
+	Comment *Comment
+}
+
+// RequestSource 搬运请求来源
+// 写操作传入的 Request 来自同一来源的读操作
+type RequestSource interface {
+	// Type 返回来源类型：github、gitlab 或 gitea
+	Type() string
+	// Identity 返回当前认证用户的用户名，用于识别本工具发布的评论
+	Identity(ctx context.Context) (string, error)
+	// PendingRequests 返回所有未关闭的搬运请求，按创建时间从早到晚排序
+	PendingRequests(ctx context.Context) ([]*Request, error)
+	// GetRequest 返回指定编号的 Issue，不是搬运请求时也会返回
+	GetRequest(ctx context.Context, number int) (*Request, error)
+	// RequesterRequests 返回用户在 since 之后创建或更新过的搬运请求，包括已关闭的请求
+	RequesterRequests(ctx context.Context, user string, since time.Time) ([]*Request, error)
+	// SearchClosed 查找带有 label 且提到 text 的已关闭请求，按更新时间从新到旧排序，最多返回 limit 个
+	// GitHub 搜索评论，Gitea 使用 Issue 索引，GitLab 只搜索标题和内容
+	SearchClosed(ctx context.Context, label, text string, limit int) ([]*Request, error)
+	// ListComments 返回 Issue 的评论，按发布时间从早到晚排序
+	ListComments(ctx context.Context, number int) ([]*Comment, error)
+	// CreateComment 为请求添加评论，返回评论 ID 用于后续修改
+	CreateComment(ctx context.Context, request *Request, body string) (int64, error)
+	// EditComment 修改评论的内容
+	EditComment(ctx context.Context, request *Request, commentID int64, body string) error
+	// AddReaction 为评论添加表情回应，content 为 +1 或 -1
+	AddReaction(ctx context.Context, request *Request, commentID int64, content string) error
+	// AddLabels 为请求添加标签，仓库中不存在的标签会自动创建
+	AddLabels(ctx context.Context, request *Request, labels []string) error
+	// RemoveLabel 移除请求的标签
+	RemoveLabel(ctx context.Context, request *Request, label string) error
+	// CloseRequest 关闭请求
+	CloseRequest(ctx context.Context, request *Request) error
+}
@@ -0,0 +1,270 @@
+// Package webhook 接收 GitHub Webhook 事件并排队交给同步服务处理
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v47/github"
+
+	"sync-image/internal/config"
+	githubclient "sync-image/internal/github"
+	"sync-image/internal/service"
+	"sync-image/pkg/logger"
+)
+
+// maxPayloadSize Webhook 请求体的最大长度，GitHub 限制事件内容不超过 25 MB
+const maxPayloadSize = 25 << 20
+
+// ServiceFactory 返回处理指定仓库事件的同步服务，不处理该仓库的事件时返回 nil
+type ServiceFactory func(owner, repo string) (service.SyncService, error)
+
+// job 排队等待处理的任务，相同 key 的任务在开始处理前只保留一个
+type job struct {
+	key string
+	run func(ctx context.Context) error
+}
+
+// Server GitHub Webhook 服务
+// 校验签名后立即返回，任务按接收顺序逐个处理，与工作流的 concurrency 排队效果相同
+// 事件按所在仓库交给对应的同步服务，使用 GitHub App 时一个服务可以处理 App 安装到的多个仓库
+type Server struct {
+	services ServiceFactory
+	secret   []byte
+	logger   logger.Logger
+
+	jobs    chan job
+	mu      sync.Mutex
+	pending map[string]bool // 已排队、尚未开始处理的任务
+}
+
+// NewServer 创建 Webhook 服务，services 返回各仓库的同步服务
+func NewServer(services ServiceFactory, cfg *config.Config, log logger.Logger) *Server {
+	return &Server{
+		services: services,
+		secret:   []byte(cfg.Server.WebhookSecret),
+		logger:   log,
+		jobs:     make(chan job, cfg.Server.QueueSize),
+		pending:  make(map[string]bool),
+	}
+}
+
+// Handler 返回 Webhook 服务的 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", s.handleWebhook)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "ok, %d jobs queued\n", len(s.jobs))
+	})
+	return mux
+}
+
+// Run 逐个处理排队的任务，直到 ctx 取消；正在处理的任务会先完成
+func (s *Server) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-s.jobs:
+			s.mu.Lock()
+			delete(s.pending, j.key)
+			s.mu.Unlock()
+
+			s.logger.Info("开始处理任务 %s", j.key)
+			// 任务不随服务停止而中断，避免 Issue 停留在处理中
+			if err := j.run(context.Background()); err != nil {
+				s.logger.Error("任务 %s 处理失败: %v", j.key, err)
+			} else {
+				s.logger.Info("任务 %s 处理完成", j.key)
+			}
+		}
+	}
+}
+
+// handleWebhook 校验并解析 Webhook 事件，需要处理时加入队列
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 只接受 HMAC-SHA256 签名，不回退到 SHA-1 的 X-Hub-Signature
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if signature == "" {
+		s.logger.Warn("Webhook 请求缺少 %s", github.SHA256SignatureHeader)
+		http.Error(w, "missing signature", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		s.logger.Warn("读取 Webhook 请求失败: %v", err)
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := github.ValidateSignature(signature, body, s.secret); err != nil {
+		s.logger.Warn("Webhook 签名校验失败: %v", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := webhookPayload(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		s.logger.Warn("读取 Webhook 事件失败: %v", err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	eventType := github.WebHookType(r)
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		s.logger.Warn("解析 Webhook 事件 %s 失败: %v", eventType, err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := event.(*github.PingEvent); ok {
+		fmt.Fprintln(w, "pong")
+		return
+	}
+
+	j, reason := s.jobFor(event)
+	if j == nil {
+		s.logger.Debug("忽略 Webhook 事件 %s (%s): %s", eventType, github.DeliveryID(r), reason)
+		fmt.Fprintf(w, "ignored: %s\n", reason)
+		return
+	}
+
+	queued, ok := s.enqueue(*j)
+	if !ok {
+		s.logger.Warn("任务队列已满，拒绝任务 %s", j.key)
+		http.Error(w, "queue is full", http.StatusServiceUnavailable)
+		return
+	}
+	if !queued {
+		fmt.Fprintf(w, "already queued: %s\n", j.key)
+		return
+	}
+
+	s.logger.Info("收到 Webhook 事件 %s (%s)，任务 %s 已加入队列", eventType, github.DeliveryID(r), j.key)
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "queued: %s\n", j.key)
+}
+
+// jobFor 返回事件对应的任务，不需要处理时返回 nil 和原因
+func (s *Server) jobFor(event interface{}) (*job, string) {
+	switch e := event.(type) {
+	case *github.IssuesEvent:
+		switch e.GetAction() {
+		case "opened", "edited", "reopened", "labeled":
+		default:
+			return nil, "action " + e.GetAction()
+		}
+		if !hasLabel(e.GetIssue(), "porter") {
+			return nil, "not a porter issue"
+		}
+		svc, reason := s.serviceFor(e.GetRepo())
+		if svc == nil {
+			return nil, reason
+		}
+		number := e.GetIssue().GetNumber()
+		return &job{
+			key: fmt.Sprintf("%s issue#%d", e.GetRepo().GetFullName(), number),
+			run: func(ctx context.Context) error { return svc.ProcessIssue(ctx, number) },
+		}, ""
+
+	case *github.IssueCommentEvent:
+		if e.GetAction() != "created" || !strings.HasPrefix(strings.TrimSpace(e.GetComment().GetBody()), "/") {
+			return nil, "not a command"
+		}
+		if !hasLabel(e.GetIssue(), "porter") {
+			return nil, "not a porter issue"
+		}
+		svc, reason := s.serviceFor(e.GetRepo())
+		if svc == nil {
+			return nil, reason
+		}
+		event := githubclient.ToCommentEvent(e)
+		return &job{
+			key: fmt.Sprintf("%s comment#%d", e.GetRepo().GetFullName(), e.GetComment().GetID()),
+			run: func(ctx context.Context) error { return svc.HandleComment(ctx, event) },
+		}, ""
+
+	case *github.LabelEvent:
+		if e.GetAction() != "created" {
+			return nil, "action " + e.GetAction()
+		}
+		svc, reason := s.serviceFor(e.GetRepo())
+		if svc == nil {
+			return nil, reason
+		}
+		// 与工作流一致，新建标签时处理所有待处理的 Issue
+		return &job{key: e.GetRepo().GetFullName() + " pending", run: svc.ProcessIssues}, ""
+	}
+
+	return nil, "unsupported event"
+}
+
+// enqueue 将任务加入队列，相同任务已在排队时 queued 为 false，队列已满时 ok 为 false
+func (s *Server) enqueue(j job) (queued, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending[j.key] {
+		return false, true
+	}
+	select {
+	case s.jobs <- j:
+		s.pending[j.key] = true
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// serviceFor 返回处理事件所在仓库的同步服务，不处理该仓库时返回 nil 和原因
+func (s *Server) serviceFor(repo *github.Repository) (service.SyncService, string) {
+	svc, err := s.services(repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		s.logger.Error("创建仓库 %s 的同步服务失败: %v", repo.GetFullName(), err)
+		return nil, "repository unavailable"
+	}
+	if svc == nil {
+		return nil, "other repository"
+	}
+	return svc, ""
+}
+
+// hasLabel 检查 Issue 是否带有指定标签
+func hasLabel(issue *github.Issue, name string) bool {
+	for _, label := range issue.Labels {
+		if label.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload 返回请求体中的事件 JSON，表单格式的 Webhook 事件在 payload 参数中
+func webhookPayload(contentType string, body []byte) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return body, nil
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(form.Get("payload")), nil
+	default:
+		return nil, fmt.Errorf("不支持的 Content-Type: %q", contentType)
+	}
+}
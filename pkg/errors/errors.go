@@ -12,6 +12,8 @@ const (
 	ConfigError ErrorType = "CONFIG_ERROR"
 	// GitHubError GitHub API 错误
 	GitHubError ErrorType = "GITHUB_ERROR"
+	// SourceError GitLab、Gitea 等请求来源的 API 错误
+	SourceError ErrorType = "SOURCE_ERROR"
 	// DockerError Docker 操作错误
 	DockerError ErrorType = "DOCKER_ERROR"
 	// RegistryError 镜像仓库错误
@@ -83,6 +85,11 @@ func NewGitHubError(message string, cause error) *AppError {
 	return WrapError(GitHubError, message, cause)
 }
 
+// NewSourceError 创建请求来源错误
+func NewSourceError(message string, cause error) *AppError {
+	return WrapError(SourceError, message, cause)
+}
+
 // NewDockerError 创建 Docker 错误
 func NewDockerError(message string, cause error) *AppError {
 	return WrapError(DockerError, message, cause)
@@ -131,6 +138,8 @@ func FormatUserError(err error, username string) string {
 			return fmt.Sprintf("@%s 镜像仓库操作失败: %s", username, appErr.Message)
 		case GitHubError:
 			return fmt.Sprintf("@%s GitHub 操作失败: %s", username, appErr.Message)
+		case SourceError:
+			return fmt.Sprintf("@%s Issue 操作失败: %s", username, appErr.Message)
 		default:
 			return fmt.Sprintf("@%s 操作失败: %s", username, appErr.Message)
 		}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// NamingStrategy 目标镜像命名策略
+type NamingStrategy string
+
+const (
+	// NamingLastSegment 只保留路径最后一段，如 node-exporter（默认，不同上游的同名镜像会冲突）
+	NamingLastSegment NamingStrategy = "last-segment"
+	// NamingFlatten 将转换后的路径用分隔符拼接为一段，如 quay.prometheus.node-exporter
+	NamingFlatten NamingStrategy = "flatten"
+	// NamingFullPath 保留转换后的完整路径，适用于支持多级命名空间的仓库
+	NamingFullPath NamingStrategy = "full-path"
+	// NamingHashSuffix 路径最后一段加上源镜像名称的短哈希，如 node-exporter-1a2b3c4d
+	NamingHashSuffix NamingStrategy = "hash-suffix"
+)
+
+// DefaultNamingSeparator flatten 策略的默认分隔符
+const DefaultNamingSeparator = "."
+
+// namingHashLength hash-suffix 策略使用的哈希长度
+const namingHashLength = 8
+
+// TargetNaming 目标仓库的命名方式
+type TargetNaming struct {
+	Strategy  NamingStrategy
+	Separator string // 仅用于 flatten 策略
+}
+
+// ParseNamingStrategy 解析命名策略，空字符串视为 last-segment
+func ParseNamingStrategy(s string) (NamingStrategy, error) {
+	switch NamingStrategy(s) {
+	case "", NamingLastSegment:
+		return NamingLastSegment, nil
+	case NamingFlatten, NamingFullPath, NamingHashSuffix:
+		return NamingStrategy(s), nil
+	default:
+		return "", fmt.Errorf("未知的命名策略: %s（可选 last-segment、flatten、full-path、hash-suffix）", s)
+	}
+}
+
+// ValidateNamingSeparator 检查分隔符是否为镜像路径中允许的分隔符
+func ValidateNamingSeparator(separator string) error {
+	switch separator {
+	case "", ".", "_", "__", "-", "--":
+		return nil
+	default:
+		return fmt.Errorf("无效的命名分隔符: %q（可选 .、_、__、-、--）", separator)
+	}
+}
+
+// BuildTargetImageNameWithNaming 按命名策略构建目标镜像名称
+// sourceImage 为标准化后的源镜像，hash-suffix 策略据此计算哈希，保证不同上游得到不同名称
+func BuildTargetImageNameWithNaming(sourceImage, transformedName, targetRegistry, targetNamespace string, naming TargetNaming) string {
+	name, suffix := splitTagSuffix(transformedName)
+
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		// 未被规则改写的仓库域名可能带端口，冒号不能出现在路径中
+		segments[i] = strings.ReplaceAll(segment, ":", "-")
+	}
+
+	var repository string
+	switch naming.Strategy {
+	case NamingFlatten:
+		separator := naming.Separator
+		if separator == "" {
+			separator = DefaultNamingSeparator
+		}
+		repository = strings.Join(nonEmpty(segments), separator)
+	case NamingFullPath:
+		repository = strings.Join(nonEmpty(segments), "/")
+	case NamingHashSuffix:
+		repository = segments[len(segments)-1] + "-" + NameHash(sourceImage)
+	default:
+		repository = segments[len(segments)-1]
+	}
+
+	result := repository + suffix
+	if targetNamespace != "" {
+		result = targetNamespace + "/" + result
+	}
+	if targetRegistry != "" {
+		result = targetRegistry + "/" + result
+	}
+
+	return result
+}
+
+// NameHash 返回镜像名称（不含标签和摘要）的短哈希
+func NameHash(image string) string {
+	name := image
+	if ref, err := ParseNormalizedReference(image); err == nil {
+		name = ref.Name()
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])[:namingHashLength]
+}
+
+// splitTagSuffix 拆分镜像名称和 ":tag" 或 "@digest" 后缀
+func splitTagSuffix(image string) (name, suffix string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image, suffix = image[:i], image[i:]
+	}
+
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i:] + suffix
+	}
+	return image, suffix
+}
+
+// nonEmpty 去掉空的路径段（转换规则可能把前缀替换为空字符串）
+func nonEmpty(segments []string) []string {
+	result := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != "" {
+			result = append(result, segment)
+		}
+	}
+	return result
+}
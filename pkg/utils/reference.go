@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 镜像引用语法（与 distribution/reference 保持一致）:
+//
+//	reference        := name [ ":" tag ] [ "@" digest ]
+//	name             := [domain '/'] path-component ['/' path-component]*
+//	domain           := host [':' port-number]
+//	host             := domain-name | IPv4address | \[ IPv6address \]
+//	domain-name      := domain-component ['.' domain-component]*
+//	domain-component := /([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])/
+//	port-number      := /[0-9]+/
+//	path-component   := alpha-numeric [separator alpha-numeric]*
+//	alpha-numeric    := /[a-z0-9]+/
+//	separator        := /[_.]|__|[-]*/
+//	tag              := /[\w][\w.-]{0,127}/
+//	digest           := digest-algorithm ":" digest-hex
+//	digest-algorithm := /[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*/
+//	digest-hex       := /[0-9a-fA-F]{32,}/
+const (
+	alphaNumeric     = `[a-z0-9]+`
+	separator        = `(?:[._]|__|[-]+)`
+	pathComponent    = alphaNumeric + `(?:` + separator + alphaNumeric + `)*`
+	domainComponent  = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	domainName       = domainComponent + `(?:\.` + domainComponent + `)*`
+	ipv6Address      = `\[(?:[a-fA-F0-9:]+)\]`
+	domainAndPort    = `(?:` + domainName + `|` + ipv6Address + `)(?::[0-9]+)?`
+	tagPattern       = `[\w][\w.-]{0,127}`
+	digestPattern    = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}`
+	pathPattern      = pathComponent + `(?:/` + pathComponent + `)*`
+	referencePattern = `^(?:(` + domainAndPort + `)/)?(` + pathPattern + `)(?::(` + tagPattern + `))?(?:@(` + digestPattern + `))?$`
+)
+
+// maxNameLength 镜像名称（域名和路径）的最大长度
+const maxNameLength = 255
+
+// DefaultDomain 未指定域名时使用的默认仓库域名
+const DefaultDomain = "docker.io"
+
+// officialRepoPrefix Docker Hub 官方镜像的命名空间
+const officialRepoPrefix = "library/"
+
+// ReferenceRegexp 匹配完整镜像引用的正则表达式
+// 子匹配依次为域名、路径、标签和摘要
+var ReferenceRegexp = regexp.MustCompile(referencePattern)
+
+// anchoredDomainRegexp 匹配单独的域名（可带端口）
+var anchoredDomainRegexp = regexp.MustCompile(`^` + domainAndPort + `$`)
+
+// remainderRegexp 匹配去掉域名后的路径、标签和摘要
+var remainderRegexp = regexp.MustCompile(`^(` + pathPattern + `)(?::(` + tagPattern + `))?(?:@(` + digestPattern + `))?$`)
+
+// Reference 镜像引用
+type Reference struct {
+	Domain string // 仓库域名（可带端口），未标准化时可能为空
+	Path   string // 仓库路径，如 library/nginx
+	Tag    string // 标签，可能为空
+	Digest string // 摘要，可能为空
+}
+
+// ParseReference 按镜像引用语法严格解析，不补全默认域名和标签
+// 只有第一段包含 "."、":"、大写字母或者为 localhost 时才被视为域名
+func ParseReference(s string) (*Reference, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("镜像引用不能为空")
+	}
+
+	domain, remainder := splitDomain(s)
+	if domain != "" && !anchoredDomainRegexp.MatchString(domain) {
+		return nil, fmt.Errorf("无效的仓库域名: %s", domain)
+	}
+
+	matches := remainderRegexp.FindStringSubmatch(remainder)
+	if matches == nil {
+		if strings.ToLower(remainder) != remainder {
+			return nil, fmt.Errorf("镜像路径不能包含大写字母: %s", s)
+		}
+		return nil, fmt.Errorf("无效的镜像引用格式: %s", s)
+	}
+
+	ref := &Reference{
+		Domain: domain,
+		Path:   matches[1],
+		Tag:    matches[2],
+		Digest: matches[3],
+	}
+	if len(ref.Name()) > maxNameLength {
+		return nil, fmt.Errorf("镜像名称超过 %d 个字符: %s", maxNameLength, s)
+	}
+
+	return ref, nil
+}
+
+// ParseNormalizedReference 解析镜像引用并补全默认域名 docker.io 和官方镜像命名空间 library
+// 不会补全默认标签；补全后的名称同样不能超过长度上限
+func ParseNormalizedReference(s string) (*Reference, error) {
+	ref, err := ParseReference(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Domain == "" || ref.Domain == "index.docker.io" {
+		ref.Domain = DefaultDomain
+	}
+	if ref.Domain == DefaultDomain && !strings.Contains(ref.Path, "/") {
+		ref.Path = officialRepoPrefix + ref.Path
+	}
+	if len(ref.Name()) > maxNameLength {
+		return nil, fmt.Errorf("镜像名称超过 %d 个字符: %s", maxNameLength, s)
+	}
+
+	return ref, nil
+}
+
+// IsValidDomain 检查是否为合法的仓库域名（可带端口）
+func IsValidDomain(domain string) bool {
+	return anchoredDomainRegexp.MatchString(domain)
+}
+
+// RedirectReference 按域名重定向表改写镜像引用，返回标准化后的引用以及是否发生了重定向
+// 重定向只应用一次，不会继续跟随目标域名的重定向；目标为空表示不重定向
+func RedirectReference(image string, redirects map[string]string) (string, bool) {
+	ref, err := ParseNormalizedReference(image)
+	if err != nil {
+		return image, false
+	}
+
+	domain, ok := redirects[ref.Domain]
+	if !ok || domain == "" || domain == ref.Domain {
+		return ref.String(), false
+	}
+	ref.Domain = domain
+	return ref.String(), true
+}
+
+// splitDomain 拆分域名和剩余部分，遵循 Docker 的域名识别规则
+func splitDomain(s string) (domain, remainder string) {
+	i := strings.Index(s, "/")
+	if i == -1 {
+		return "", s
+	}
+
+	first := s[:i]
+	if strings.ContainsAny(first, ".:") || first == "localhost" || strings.ToLower(first) != first {
+		return first, s[i+1:]
+	}
+	return "", s
+}
+
+// Name 返回不含标签和摘要的镜像名称
+func (r *Reference) Name() string {
+	if r.Domain == "" {
+		return r.Path
+	}
+	return r.Domain + "/" + r.Path
+}
+
+// String 返回完整的镜像引用
+func (r *Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// NameWithTag 返回不含摘要的镜像引用
+func (r *Reference) NameWithTag() string {
+	if r.Tag == "" {
+		return r.Name()
+	}
+	return r.Name() + ":" + r.Tag
+}
+
+// TagOrDigest 返回用于拉取清单的引用，优先使用摘要，都为空时返回 latest
+func (r *Reference) TagOrDigest() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	if r.Tag != "" {
+		return r.Tag
+	}
+	return "latest"
+}
+
+// LastPathComponent 返回路径的最后一段
+func (r *Reference) LastPathComponent() string {
+	return r.Path[strings.LastIndex(r.Path, "/")+1:]
+}
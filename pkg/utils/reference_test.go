@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseReference(f *testing.F) {
+	for _, seed := range []string{
+		"nginx",
+		"nginx:1.25",
+		"library/nginx:latest",
+		"docker.io/library/nginx:1.25",
+		"index.docker.io/nginx",
+		"localhost/foo",
+		"localhost:5000/foo",
+		"localhost:5000/foo/bar:v1",
+		"gcr.io/google-containers/pause:3.9",
+		"registry.k8s.io/kube-apiserver:v1.28.0",
+		"[::1]:5000/foo:bar",
+		"nginx@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"quay.io/coreos/etcd:v3.5.9@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"Foo/bar",
+		"foo/Bar",
+		"nginx:",
+		"",
+	} {
+		f.Add(seed)
+	}
+	// 补全默认域名后超过长度上限
+	f.Add(strings.Repeat("a", 250))
+
+	f.Fuzz(func(t *testing.T, s string) {
+		ref, err := ParseReference(s)
+		if err == nil {
+			again, err := ParseReference(ref.String())
+			if err != nil {
+				t.Fatalf("ParseReference(%q).String() = %q does not parse: %v", s, ref.String(), err)
+			}
+			if *again != *ref {
+				t.Fatalf("ParseReference(%q) = %+v, re-parsed as %+v", s, *ref, *again)
+			}
+		}
+
+		normalized, err := ParseNormalizedReference(s)
+		if err != nil {
+			return
+		}
+		again, err := ParseNormalizedReference(normalized.String())
+		if err != nil {
+			t.Fatalf("ParseNormalizedReference(%q).String() = %q does not parse: %v", s, normalized.String(), err)
+		}
+		if *again != *normalized {
+			t.Fatalf("ParseNormalizedReference is not idempotent for %q: %+v then %+v", s, *normalized, *again)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxImagesPerIssue 单个 Issue 最多可以请求的镜像数量
+const MaxImagesPerIssue = 20
+
+// ImageRequest Issue 中请求同步的单个镜像
+type ImageRequest struct {
+	Image    string   // 镜像名称
+	Platform string   // 逗号分隔的平台列表，为空时使用默认配置
+	Tags     []string // 同步后额外添加的目标镜像标签
+	Target   string   // 目标仓库名称（不含仓库地址、命名空间和标签），为空时按转换规则生成
+}
+
+// 镜像搬运 Issue 表单（.github/ISSUE_TEMPLATE/porter.yml）中各字段的标题，修改表单时需要同步修改
+const (
+	FormFieldImage     = "镜像名称"
+	FormFieldPlatforms = "架构"
+	FormFieldNotes     = "备注"
+)
+
+// formNoResponse GitHub 渲染表单时未填写字段的内容
+const formNoResponse = "_No response_"
+
+// formHeadingRegexp 匹配表单渲染出的字段标题
+var formHeadingRegexp = regexp.MustCompile(`(?m)^###[ \t]+(.+?)[ \t]*\r?$`)
+
+// requestBlockRegexp 匹配 Issue 内容中的第一个 yaml 代码块
+// 只识别标注了 yaml 的代码块，避免把日志等其他代码块当作镜像列表
+var requestBlockRegexp = regexp.MustCompile("(?s)```ya?ml[ \t]*\r?\n(.*?)```")
+
+// 请求中各字段的格式
+var (
+	platformRegexp = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(?:/[a-z0-9.]+)?$`)
+	tagRegexp      = regexp.MustCompile(`^` + tagPattern + `$`)
+	targetRegexp   = regexp.MustCompile(`^` + pathPattern + `$`)
+)
+
+// IsValidPlatform 检查是否为合法的平台，如 linux/amd64、linux/arm/v7
+func IsValidPlatform(platform string) bool {
+	return platformRegexp.MatchString(platform)
+}
+
+// IsValidTag 检查是否为合法的镜像标签
+func IsValidTag(tag string) bool {
+	return tagRegexp.MatchString(tag)
+}
+
+// stringList 可以写成逗号分隔的字符串或字符串列表的字段
+type stringList []string
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*l = nil
+		for _, item := range strings.Split(value.Value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*l = append(*l, item)
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := value.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		return fmt.Errorf("第 %d 行: 应为字符串或列表", value.Line)
+	}
+}
+
+// requestItem 代码块中的单个镜像，可以只写镜像名称
+type requestItem struct {
+	Image     string     `yaml:"image"`
+	Platforms stringList `yaml:"platforms"`
+	Tags      stringList `yaml:"tags"`
+	Target    string     `yaml:"target"`
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler，支持只写镜像名称的简写
+func (i *requestItem) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*i = requestItem{Image: value.Value}
+		return nil
+	}
+
+	type plain requestItem
+	return value.Decode((*plain)(i))
+}
+
+// ParseIssueForm 解析 Issue 表单渲染出的内容，返回字段标题到内容的映射，未填写的字段内容为空
+// 表单每个字段渲染为 "### 标题" 加上字段内容
+func ParseIssueForm(body string) map[string]string {
+	fields := make(map[string]string)
+	headings := formHeadingRegexp.FindAllStringSubmatchIndex(body, -1)
+	for n, heading := range headings {
+		end := len(body)
+		if n+1 < len(headings) {
+			end = headings[n+1][0]
+		}
+
+		value := strings.TrimSpace(body[heading[1]:end])
+		if value == formNoResponse {
+			value = ""
+		}
+		fields[body[heading[2]:heading[3]]] = value
+	}
+	return fields
+}
+
+// ParseIssueFormRequest 从搬运镜像表单中解析请求的镜像，Issue 不是通过表单创建时 found 为 false
+// 下拉框多选的架构渲染为 "linux/amd64, linux/arm64"，转换为逗号分隔的平台列表
+func ParseIssueFormRequest(body string) (request ImageRequest, found bool) {
+	fields := ParseIssueForm(body)
+	image, ok := fields[FormFieldImage]
+	if !ok {
+		return ImageRequest{}, false
+	}
+
+	request.Image = strings.TrimSpace(image)
+	if ref, err := ParseReference(request.Image); err == nil {
+		request.Image = ref.String()
+	}
+
+	var platforms []string
+	for _, platform := range strings.Split(fields[FormFieldPlatforms], ",") {
+		if platform = strings.TrimSpace(platform); platform != "" {
+			platforms = append(platforms, platform)
+		}
+	}
+	request.Platform = strings.Join(platforms, ",")
+
+	return request, true
+}
+
+// ParseIssueBody 解析 Issue 内容中的镜像列表代码块
+// 代码块可以是镜像列表，也可以是包含 images 列表的映射:
+//
+//	```yaml
+//	images:
+//	  - nginx:1.25
+//	  - image: quay.io/prometheus/node-exporter:v1.7.0
+//	    platforms: linux/amd64,linux/arm64
+//	    tags: [latest]
+//	    target: node-exporter
+//	```
+//
+// 没有 yaml 代码块时 found 为 false；存在代码块时整体校验，任一镜像无效都返回错误
+// allowTarget 为 false 时不允许指定 target 和 tags，避免请求者覆盖其他镜像的目标仓库或标签
+func ParseIssueBody(body string, allowTarget bool) (requests []ImageRequest, found bool, err error) {
+	match := requestBlockRegexp.FindStringSubmatch(body)
+	if match == nil {
+		return nil, false, nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(match[1]), &root); err != nil {
+		return nil, true, fmt.Errorf("无法解析镜像列表: %w", err)
+	}
+
+	var items []requestItem
+	node := &root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		err = node.Decode(&items)
+	case yaml.MappingNode:
+		var doc struct {
+			Images []requestItem `yaml:"images"`
+		}
+		err = node.Decode(&doc)
+		items = doc.Images
+	default:
+		err = fmt.Errorf("应为镜像列表或包含 images 的映射")
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("无法解析镜像列表: %w", err)
+	}
+
+	requests, err = validateRequests(items, allowTarget)
+	return requests, true, err
+}
+
+// validateRequests 校验并规范化所有镜像请求，返回所有错误
+func validateRequests(items []requestItem, allowTarget bool) ([]ImageRequest, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("镜像列表为空")
+	}
+	if len(items) > MaxImagesPerIssue {
+		return nil, fmt.Errorf("单个 Issue 最多请求 %d 个镜像，当前 %d 个", MaxImagesPerIssue, len(items))
+	}
+
+	var errs []error
+	requests := make([]ImageRequest, 0, len(items))
+	seen := make(map[string]int)
+	for n, item := range items {
+		prefix := fmt.Sprintf("第 %d 个镜像", n+1)
+
+		image := strings.TrimSpace(item.Image)
+		ref, err := ParseNormalizedReference(image)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: 无效的镜像名称 %q", prefix, image))
+			continue
+		}
+
+		for _, platform := range item.Platforms {
+			if !IsValidPlatform(platform) {
+				errs = append(errs, fmt.Errorf("%s: 无效的平台 %q", prefix, platform))
+			}
+		}
+		if len(item.Tags) > 0 && !allowTarget {
+			errs = append(errs, fmt.Errorf("%s: 只有仓库维护者可以指定额外标签 tags（未启用映射索引时无法检查目标仓库是否已被其他镜像占用）", prefix))
+		}
+		for _, tag := range item.Tags {
+			if !IsValidTag(tag) {
+				errs = append(errs, fmt.Errorf("%s: 无效的标签 %q", prefix, tag))
+			}
+		}
+		target := strings.Trim(strings.TrimSpace(item.Target), "/")
+		switch {
+		case target == "":
+		case !allowTarget:
+			errs = append(errs, fmt.Errorf("%s: 只有仓库维护者可以指定目标仓库名称 target（未启用映射索引时无法检查目标仓库是否已被其他镜像占用）", prefix))
+		case !targetRegexp.MatchString(target):
+			errs = append(errs, fmt.Errorf("%s: 无效的目标仓库名称 %q", prefix, target))
+		}
+
+		// 同一源镜像同步到同一目标仓库视为重复
+		key := ref.String() + " -> " + target
+		if first, ok := seen[key]; ok {
+			errs = append(errs, fmt.Errorf("%s: 与第 %d 个镜像重复", prefix, first))
+		}
+		seen[key] = n + 1
+
+		requests = append(requests, ImageRequest{
+			Image:    image,
+			Platform: strings.Join(item.Platforms, ","),
+			Tags:     item.Tags,
+			Target:   target,
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return requests, nil
+}
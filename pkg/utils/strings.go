@@ -1,105 +1,120 @@
 package utils
 
 import (
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+// RewriteRule 镜像名称转换规则
+type RewriteRule struct {
+	Name        string // 规则名称，为空时使用 Pattern
+	Pattern     string // 正则表达式
+	Replacement string // 替换内容，支持捕获组引用
+	Destination string // 匹配时使用的目标仓库名称，为空表示默认目标
+}
+
+// DisplayName 返回规则名称，未命名时返回正则表达式
+func (r RewriteRule) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Pattern
+}
+
+// compiledRule 编译后的转换规则
+type compiledRule struct {
+	RewriteRule
+	re *regexp.Regexp
+}
+
 // ImageNameParser 镜像名称解析器
 type ImageNameParser struct {
-	compiledRules map[string]*regexp.Regexp
+	rules      []compiledRule
+	firstMatch bool
 }
 
 // NewImageNameParser 创建新的镜像名称解析器
-func NewImageNameParser(rules map[string]string) *ImageNameParser {
-	compiled := make(map[string]*regexp.Regexp)
-	for pattern := range rules {
-		if re, err := regexp.Compile(pattern); err == nil {
-			compiled[pattern] = re
+// rules 按给定顺序应用，firstMatch 为 true 时只应用第一条匹配的规则，否则依次应用所有匹配的规则
+func NewImageNameParser(rules []RewriteRule, firstMatch bool) *ImageNameParser {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if re, err := regexp.Compile(rule.Pattern); err == nil {
+			compiled = append(compiled, compiledRule{RewriteRule: rule, re: re})
 		}
 	}
 
 	return &ImageNameParser{
-		compiledRules: compiled,
+		rules:      compiled,
+		firstMatch: firstMatch,
 	}
 }
 
 // NormalizeImageName 标准化镜像名称
+// 补全默认域名 docker.io 和官方镜像命名空间 library，无法解析时原样返回，由调用方校验
 func (p *ImageNameParser) NormalizeImageName(imageName string) string {
 	imageName = strings.TrimSpace(imageName)
 
-	// 处理 Docker Hub 默认镜像
-	if !strings.Contains(imageName, ".") ||
-		(!strings.Contains(imageName, "/") && strings.Index(imageName, ".") > strings.Index(imageName, "/")) {
-		imageName = "docker.io/" + imageName
-	} else if !strings.Contains(imageName, "/") {
-		// 对于只有名称加标签的情况，如"nginx:alpine"，直接添加默认域名
-		imageName = "docker.io/library/" + imageName
+	ref, err := ParseNormalizedReference(imageName)
+	if err != nil {
+		return imageName
 	}
 
-	return imageName
+	return ref.String()
 }
 
 // TransformImageName 根据规则转换镜像名称
-func (p *ImageNameParser) TransformImageName(imageName string, rules map[string]string) string {
+func (p *ImageNameParser) TransformImageName(imageName string) string {
+	result, _ := p.TransformImageNameWithTrace(imageName)
+	return result
+}
+
+// TransformImageNameWithTrace 根据规则转换镜像名称，同时返回实际生效的规则
+func (p *ImageNameParser) TransformImageNameWithTrace(imageName string) (string, []RewriteRule) {
 	result := imageName
 
 	// 移除摘要部分
-	if strings.Contains(result, "@") {
+	if ref, err := ParseReference(result); err == nil {
+		result = ref.NameWithTag()
+	} else if strings.Contains(result, "@") {
 		result = strings.Split(result, "@")[0]
 	}
 
-	// 应用转换规则
-	for pattern, replacement := range rules {
-		if re, exists := p.compiledRules[pattern]; exists {
-			result = re.ReplaceAllString(result, replacement)
+	// 按顺序应用转换规则
+	var applied []RewriteRule
+	for _, rule := range p.rules {
+		if !rule.re.MatchString(result) {
+			continue
+		}
+
+		result = rule.re.ReplaceAllString(result, rule.Replacement)
+		applied = append(applied, rule.RewriteRule)
+
+		if p.firstMatch {
+			break
 		}
 	}
 
-	return result
+	return result, applied
 }
 
 // ExtractImageInfo 从镜像名称中提取信息
 func ExtractImageInfo(imageName string) (registry, namespace, repository, tag string) {
-	// 分离标签
-	parts := strings.Split(imageName, ":")
-	nameWithoutTag := parts[0]
-	if len(parts) > 1 {
-		tag = parts[1]
-	} else {
-		tag = "latest"
-	}
-
-	// 分离注册表、命名空间和仓库
-	segments := strings.Split(nameWithoutTag, "/")
-
-	switch len(segments) {
-	case 1:
-		// 只有仓库名，默认为 Docker Hub
-		registry = "docker.io"
-		namespace = "library"
-		repository = segments[0]
-	case 2:
-		if strings.Contains(segments[0], ".") {
-			// 第一部分包含点，认为是注册表
-			registry = segments[0]
-			namespace = ""
-			repository = segments[1]
-		} else {
-			// 默认为 Docker Hub
-			registry = "docker.io"
-			namespace = segments[0]
-			repository = segments[1]
-		}
-	case 3:
-		registry = segments[0]
-		namespace = segments[1]
-		repository = segments[2]
-	default:
-		// 更多段，取第一个为注册表，最后一个为仓库，中间的合并为命名空间
-		registry = segments[0]
-		repository = segments[len(segments)-1]
-		namespace = strings.Join(segments[1:len(segments)-1], "/")
+	tag = "latest"
+
+	ref, err := ParseNormalizedReference(imageName)
+	if err != nil {
+		return
+	}
+
+	registry = ref.Domain
+	repository = ref.LastPathComponent()
+	if i := strings.LastIndex(ref.Path, "/"); i >= 0 {
+		namespace = ref.Path[:i]
+	}
+	if ref.Tag != "" {
+		tag = ref.Tag
 	}
 
 	return
@@ -127,8 +142,11 @@ func BuildTargetImageName(transformedName, targetRegistry, targetNamespace strin
 
 // ParseIssueTitle 解析 Issue 标题
 func ParseIssueTitle(title string) (imageName, platform string) {
-	// 去掉前缀 [PORTER] 并去除前后空格
-	cleaned := strings.TrimSpace(strings.Replace(title, "[PORTER]", "", 1))
+	// 去掉前缀 [PORTER]（不区分大小写）并去除前后空格
+	cleaned := strings.TrimSpace(title)
+	if len(cleaned) >= len("[PORTER]") && strings.EqualFold(cleaned[:len("[PORTER]")], "[PORTER]") {
+		cleaned = strings.TrimSpace(cleaned[len("[PORTER]"):])
+	}
 
 	// 检查是否包含平台信息
 	parts := strings.Split(cleaned, "|")
@@ -138,6 +156,11 @@ func ParseIssueTitle(title string) (imageName, platform string) {
 		platform = strings.TrimSpace(parts[1])
 	}
 
+	// 按镜像引用语法规范化，无法解析时原样返回，由调用方校验
+	if ref, err := ParseReference(imageName); err == nil {
+		imageName = ref.String()
+	}
+
 	return
 }
 
@@ -166,30 +189,51 @@ func SanitizeString(s string) string {
 	return strings.TrimSpace(s)
 }
 
-// IsValidImageName 验证镜像名称是否有效
+// IsValidImageName 验证镜像名称是否符合镜像引用语法
 func IsValidImageName(imageName string) bool {
 	if imageName == "" {
 		return false
 	}
 
-	// 长度限制
-	if len(imageName) > 255 {
-		return false
+	_, err := ParseReference(imageName)
+	return err == nil
+}
+
+// FormatBytes 将字节数格式化为易读的字符串
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
 	}
 
-	// 基本的镜像名称格式验证
-	validPattern := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:/-]*[a-zA-Z0-9]$`)
-	if !validPattern.MatchString(imageName) {
-		return false
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
 
-	// 检查是否包含危险字符
-	dangerousChars := []string{";", "&", "|", "`", "$", "(", ")", "{", "}", "[", "]", "<", ">"}
-	for _, char := range dangerousChars {
-		if strings.Contains(imageName, char) {
-			return false
-		}
+// ParseBytes 解析带单位的字节数，如 "512MB"、"10GiB"，单位按 1024 换算，没有单位时为字节
+func ParseBytes(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	number := strings.TrimRight(value, "BIKMGT ")
+	unit := strings.TrimSpace(strings.TrimPrefix(value, number))
+
+	size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", s)
 	}
 
-	return true
+	multiplier := map[string]float64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+		"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+	}[unit]
+	if multiplier == 0 {
+		return 0, fmt.Errorf("无效的大小单位: %s", s)
+	}
+	return int64(size * multiplier), nil
 }
@@ -1,9 +1,73 @@
 #!/bin/sh
+# 从镜像仓库拉取已同步的镜像，并重新打上原始镜像名称
+#
+# 本脚本只实现了默认转换规则，且只支持 docker 和 ctr。
+# 使用自定义规则、destinations 或其他容器运行时时请使用 sync-image pull，
+# 它与同步程序读取相同的配置，计算出的镜像名称始终一致。
+#
+# 环境变量:
+#   MIRROR_REGISTRY   镜像仓库地址，默认 docker.io
+#   MIRROR_NAMESPACE  镜像命名空间，默认 tw-ops
+#   NAMING_STRATEGY   目标镜像命名策略，需与同步配置的 naming 一致:
+#                     last-segment（默认）、flatten、full-path、hash-suffix
+#   NAMING_SEPARATOR  flatten 策略的分隔符，默认 .
 
 k8s_img=$1
-mirror_img=$(echo ${k8s_img}|
-        sed 's/quay\.io/tw-ops/\/quay/g;s/ghcr\.io/tw-ops/\/ghcr/g;s/registry\.k8s\.io/tw-ops/\/google-containers/g;s/k8s\.gcr\.io/tw-ops/\/google-containers/g;s/gcr\.io/tw-ops//g;s/\//\./g;s/ /\n/g;s/tw-ops/\./tw-ops/\//g' |
-        uniq)
+mirror_registry=${MIRROR_REGISTRY:-docker.io}
+mirror_namespace=${MIRROR_NAMESPACE:-tw-ops}
+naming_strategy=${NAMING_STRATEGY:-last-segment}
+naming_separator=${NAMING_SEPARATOR:-.}
+
+if [ -z "${k8s_img}" ]; then
+  echo "usage: $0 <image>"
+  exit 1
+fi
+
+# 拆分标签，摘要在同步时会被去掉
+name=${k8s_img%%@*}
+tag=""
+case "${name##*/}" in
+  *:*)
+    tag=":${name##*:}"
+    name=${name%:*}
+    ;;
+esac
+
+# 补全默认域名 docker.io 和官方镜像命名空间 library
+first=${name%%/*}
+if [ "${first}" = "${name}" ]; then
+  name="docker.io/${name}"
+else
+  case "${first}" in
+    *.*|*:*|localhost) ;;
+    *) name="docker.io/${name}" ;;
+  esac
+fi
+case "${name}" in
+  index.docker.io/*) name="docker.io/${name#index.docker.io/}" ;;
+esac
+case "${name}" in
+  docker.io/*/*) ;;
+  docker.io/*) name="docker.io/library/${name#docker.io/}" ;;
+esac
+
+# 应用默认转换规则，与 config.example.yaml 中的 rules 保持一致
+transformed=$(echo "${name}" |
+        sed 's/^gcr\.io//;s/^docker\.io/docker/;s/^k8s\.gcr\.io/google-containers/;s/^registry\.k8s\.io/google-containers/;s/^quay\.io/quay/;s/^ghcr\.io/ghcr/;s/^\/*//' |
+        tr ':' '-')
+
+case "${naming_strategy}" in
+  last-segment) repository=${transformed##*/} ;;
+  flatten) repository=$(echo "${transformed}" | sed "s/\//${naming_separator}/g") ;;
+  full-path) repository=${transformed} ;;
+  hash-suffix) repository="${transformed##*/}-$(printf '%s' "${name}" | sha256sum | cut -c1-8)" ;;
+  *)
+    echo "unknown naming strategy: ${naming_strategy}"
+    exit 1
+    ;;
+esac
+
+mirror_img="${mirror_registry}/${mirror_namespace}/${repository}${tag}"
 
 if [ -x "$(command -v docker)" ]; then
   docker pull ${mirror_img}
@@ -12,8 +76,8 @@ if [ -x "$(command -v docker)" ]; then
 fi
 
 if [ -x "$(command -v ctr)" ]; then
-  ctr -n k8s.io image pull docker.io/${mirror_img}
-  ctr -n k8s.io image tag docker.io/${mirror_img} ${k8s_img}
+  ctr -n k8s.io image pull ${mirror_img}
+  ctr -n k8s.io image tag ${mirror_img} ${k8s_img}
   exit 0
 fi
 